@@ -0,0 +1,39 @@
+//go:build !wasm && !wasi
+
+package badger
+
+import (
+	"io"
+
+	"github.com/darkweak/storages/core"
+)
+
+// Number of pending writes allowed while loading a backup.
+const restoreMaxPendingWrites = 256
+
+var _ core.BackupStorer = (*Badger)(nil)
+
+// Backup writes a full backup of the live entries to the writer. It can run
+// while the provider serves requests, the incremental backups are still
+// available through provider.DB.Backup.
+func (provider *Badger) Backup(w io.Writer) error {
+	_, err := provider.DB.Backup(w, 0)
+	if err != nil {
+		provider.logger.Errorf("Impossible to backup the Badger DB, %v", err)
+	}
+
+	return err
+}
+
+// Restore loads a backup written by Backup, the entries keep their expiration.
+func (provider *Badger) Restore(r io.Reader) error {
+	provider.writes.Add(1)
+
+	if err := provider.DB.Load(r, restoreMaxPendingWrites); err != nil {
+		provider.logger.Errorf("Impossible to restore the Badger DB, %v", err)
+
+		return err
+	}
+
+	return nil
+}
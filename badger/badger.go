@@ -8,9 +8,9 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
-	"regexp"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"dario.cat/mergo"
@@ -23,13 +23,19 @@ import (
 // Badger provider type.
 type Badger struct {
 	*badger.DB
-	stale  time.Duration
-	logger core.Logger
+	stale       time.Duration
+	logger      core.Logger
+	uid         string
+	refs        int
+	writes      atomic.Uint64
+	maintenance *maintenance
 }
 
 var (
-	enabledBadgerInstances               = sync.Map{}
-	_                      badger.Logger = (*badgerLogger)(nil)
+	enabledBadgerInstances = sync.Map{}
+	instancesMu            sync.Mutex
+	_                      badger.Logger      = (*badgerLogger)(nil)
+	_                      core.PrefixDeleter = (*Badger)(nil)
 )
 
 type badgerLogger struct {
@@ -78,8 +84,14 @@ func Factory(badgerConfiguration core.CacheProvider, logger core.Logger, stale t
 
 	uid := badgerOptions.Dir + badgerOptions.ValueDir + stale.String()
 
+	instancesMu.Lock()
+	defer instancesMu.Unlock()
+
 	if instance, ok := enabledBadgerInstances.Load(uid); ok {
-		return instance.(*Badger), nil
+		i := instance.(*Badger)
+		i.refs++
+
+		return i, nil
 	}
 
 	db, e := badger.Open(badgerOptions)
@@ -87,9 +99,20 @@ func Factory(badgerConfiguration core.CacheProvider, logger core.Logger, stale t
 		logger.Error("Impossible to open the Badger DB.", e)
 	}
 
-	i := &Badger{DB: db, logger: logger, stale: stale}
+	i := &Badger{
+		DB:          db,
+		logger:      logger,
+		stale:       stale,
+		uid:         uid,
+		refs:        1,
+		maintenance: &maintenance{maintenanceConfiguration: parseMaintenanceConfiguration(badgerConfiguration.Configuration)},
+	}
 	enabledBadgerInstances.Store(uid, i)
 
+	if db != nil {
+		i.startMaintenance()
+	}
+
 	return i, nil
 }
 
@@ -271,6 +294,8 @@ func (provider *Badger) SetMultiLevel(baseKey, variedKey string, value []byte, v
 
 		return btx.SetEntry(badger.NewEntry([]byte(mappingKey), val))
 	})
+	provider.writes.Add(1)
+
 	if err != nil {
 		provider.logger.Errorf("Impossible to set value into Badger, %v", err)
 	}
@@ -283,6 +308,8 @@ func (provider *Badger) Set(key string, value []byte, duration time.Duration) er
 	err := provider.DB.Update(func(txn *badger.Txn) error {
 		return txn.SetEntry(badger.NewEntry([]byte(key), value).WithTTL(duration))
 	})
+	provider.writes.Add(1)
+
 	if err != nil {
 		provider.logger.Errorf("Impossible to set value into Badger, %v", err)
 	}
@@ -292,35 +319,74 @@ func (provider *Badger) Set(key string, value []byte, duration time.Duration) er
 
 // Delete method will delete the response in Badger provider if exists corresponding to key param.
 func (provider *Badger) Delete(key string) {
+	provider.writes.Add(1)
+
 	_ = provider.DB.Update(func(txn *badger.Txn) error {
 		return txn.Delete([]byte(key))
 	})
 }
 
 // DeleteMany method will delete the responses in Badger provider if exists corresponding to the regex key param.
+// The anchored patterns only iterate over their literal prefix, and are dropped at once when the prefix is enough to match.
 func (provider *Badger) DeleteMany(key string) {
-	rgKey, e := regexp.Compile(key)
-
+	matcher, e := core.ParseMatcher(key)
 	if e != nil {
 		return
 	}
 
-	_ = provider.DB.View(func(txn *badger.Txn) error {
+	prefix, exact := matcher.Prefix()
+	if exact && prefix != "" {
+		if err := provider.DeletePrefix(prefix); err != nil {
+			provider.logger.Errorf("Impossible to drop the prefix %s in Badger, %v", prefix, err)
+		}
+
+		return
+	}
+
+	provider.writes.Add(1)
+
+	batch := provider.DB.NewWriteBatch()
+	p := []byte(prefix)
+
+	err := provider.DB.View(func(txn *badger.Txn) error {
 		opts := badger.DefaultIteratorOptions
 		opts.PrefetchValues = false
+		opts.Prefix = p
 		it := txn.NewIterator(opts)
 
 		defer it.Close()
 
-		for it.Rewind(); it.Valid(); it.Next() {
-			k := string(it.Item().Key())
-			if rgKey.MatchString(k) {
-				provider.Delete(k)
+		for it.Seek(p); it.ValidForPrefix(p); it.Next() {
+			if k := it.Item().KeyCopy(nil); matcher.Match(string(k)) {
+				if err := batch.Delete(k); err != nil {
+					return err
+				}
 			}
 		}
 
 		return nil
 	})
+	if err != nil {
+		batch.Cancel()
+		provider.logger.Errorf("Impossible to delete the keys matching %s in Badger, %v", key, err)
+
+		return
+	}
+
+	if err = batch.Flush(); err != nil {
+		provider.logger.Errorf("Impossible to delete the keys matching %s in Badger, %v", key, err)
+	}
+}
+
+// DeletePrefix method will delete every key starting with the prefix without scanning the whole keyspace.
+func (provider *Badger) DeletePrefix(prefix string) error {
+	if prefix == "" {
+		return core.ErrEmptyPrefix
+	}
+
+	provider.writes.Add(1)
+
+	return provider.DB.DropPrefix([]byte(prefix))
 }
 
 // Init method will.
@@ -330,5 +396,37 @@ func (provider *Badger) Init() error {
 
 // Reset method will reset or close provider.
 func (provider *Badger) Reset() error {
+	instancesMu.Lock()
+	opened := provider.refs > 0
+	instancesMu.Unlock()
+
+	// The value log GC must not run while the data is dropped, it's only
+	// restarted if it was running on a DB still opened.
+	if provider.stopMaintenance() && opened {
+		defer provider.startMaintenance()
+	}
+
 	return provider.DB.DropAll()
 }
+
+// Close releases the provider, the maintenance is stopped and the DB closed once no provider uses it anymore.
+func (provider *Badger) Close() error {
+	instancesMu.Lock()
+	defer instancesMu.Unlock()
+
+	if provider.refs == 0 {
+		return nil
+	}
+
+	provider.refs--
+	if provider.refs > 0 {
+		return nil
+	}
+
+	provider.stopMaintenance()
+	enabledBadgerInstances.Delete(provider.uid)
+	core.UnregisterStorage(provider)
+	provider.logger.Infof("Close the Badger DB, maintenance report: %s", provider.MaintenanceReport())
+
+	return provider.DB.Close()
+}
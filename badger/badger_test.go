@@ -3,6 +3,10 @@
 package badger_test
 
 import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
 	"testing"
 	"time"
 
@@ -132,3 +136,134 @@ func TestBadger_Init(t *testing.T) {
 		t.Error("Impossible to init Badger provider")
 	}
 }
+
+func TestBadger_Maintenance(t *testing.T) {
+	// Small value logs and memtables so the overwritten values fill whole value log files.
+	client, _ := badger.Factory(core.CacheProvider{
+		Configuration: map[string]interface{}{
+			"Dir":                t.TempDir(),
+			"ValueLogGCInterval": "100ms",
+			"FlattenOnIdle":      true,
+			"ValueThreshold":     1 << 10,
+			"ValueLogFileSize":   1 << 20,
+			"MemTableSize":       1 << 13,
+		},
+	}, zap.NewNop().Sugar(), 0)
+
+	value := []byte(strings.Repeat("a", 8<<10))
+
+	for range 4 {
+		for i := range 256 {
+			_ = client.Set(fmt.Sprintf("Overwritten-%d", i), value, 20*time.Second)
+		}
+	}
+
+	provider := client.(*badger.Badger)
+
+	for start := time.Now(); time.Since(start) < 5*time.Second; time.Sleep(100 * time.Millisecond) {
+		if report := provider.MaintenanceReport(); report.ReclaimedBytes > 0 && report.Flattens > 0 {
+			break
+		}
+	}
+
+	if report := provider.MaintenanceReport(); report.Runs == 0 || report.Flattens == 0 || report.RewrittenFiles == 0 || report.ReclaimedBytes == 0 {
+		t.Errorf("The maintenance should have run, reclaimed the overwritten values and flattened the idle DB, got %s", report)
+	}
+
+	if err := client.Reset(); err != nil {
+		t.Errorf("Impossible to reset the Badger provider: %v", err)
+	}
+
+	core.RegisterStorage(provider)
+
+	if err := provider.Close(); err != nil {
+		t.Errorf("Impossible to close the Badger provider: %v", err)
+	}
+
+	if core.GetRegisteredStorer(fmt.Sprintf("%s-%s", provider.Name(), provider.Uuid())) != nil {
+		t.Error("The closed provider should be unregistered")
+	}
+
+	runs := provider.MaintenanceReport().Runs
+
+	time.Sleep(300 * time.Millisecond)
+
+	if provider.MaintenanceReport().Runs != runs {
+		t.Error("The maintenance should be stopped once closed")
+	}
+
+	_ = client.Reset()
+
+	time.Sleep(300 * time.Millisecond)
+
+	if provider.MaintenanceReport().Runs != runs {
+		t.Error("The maintenance should not be restarted by a Reset once closed")
+	}
+}
+
+func TestBadger_DeleteMany(t *testing.T) {
+	client, _ := getBadgerInstance()
+
+	for _, key := range []string{"GET-http-a.com-/1", "GET-http-a.com-/2", "GET-http-b.com-/1", "GET-http-b.com-/22"} {
+		_ = client.Set(key, []byte(baseValue), 20*time.Second)
+	}
+
+	// Dropped at once by prefix.
+	client.DeleteMany("^GET-http-a\\.com-")
+
+	// Iterated over the prefix only.
+	client.DeleteMany("^GET-http-b\\.com-/[0-9]$")
+
+	for key, exists := range map[string]bool{
+		"GET-http-a.com-/1":  false,
+		"GET-http-a.com-/2":  false,
+		"GET-http-b.com-/1":  false,
+		"GET-http-b.com-/22": true,
+	} {
+		if (len(client.Get(key)) != 0) != exists {
+			t.Errorf("The key %s existence should be %t", key, exists)
+		}
+	}
+
+	_ = client.Set("GET-http-c.com-/1", []byte(baseValue), 20*time.Second)
+	if err := client.(core.PrefixDeleter).DeletePrefix("GET-http-c.com-"); err != nil {
+		t.Errorf("Impossible to delete the prefix: %v", err)
+	}
+
+	if len(client.Get("GET-http-c.com-/1")) != 0 {
+		t.Error("The key GET-http-c.com-/1 should be deleted")
+	}
+
+	if err := client.(core.PrefixDeleter).DeletePrefix(""); !errors.Is(err, core.ErrEmptyPrefix) {
+		t.Errorf("The empty prefix should be rejected, got %v", err)
+	}
+
+	if len(client.Get("GET-http-b.com-/22")) == 0 {
+		t.Error("The empty prefix shouldn't delete any key")
+	}
+}
+
+func TestBadger_BackupRestore(t *testing.T) {
+	source, _ := badger.Factory(core.CacheProvider{Configuration: map[string]interface{}{"Dir": t.TempDir()}}, zap.NewNop().Sugar(), 0)
+	target, _ := badger.Factory(core.CacheProvider{Configuration: map[string]interface{}{"Dir": t.TempDir()}}, zap.NewNop().Sugar(), 0)
+
+	defer func() {
+		_ = source.(*badger.Badger).Close()
+		_ = target.(*badger.Badger).Close()
+	}()
+
+	_ = source.Set(byteKey, []byte(baseValue), 20*time.Second)
+
+	backup := new(bytes.Buffer)
+	if err := source.(core.BackupStorer).Backup(backup); err != nil {
+		t.Fatalf("Impossible to backup the Badger provider: %v", err)
+	}
+
+	if err := target.(core.BackupStorer).Restore(backup); err != nil {
+		t.Fatalf("Impossible to restore the Badger provider: %v", err)
+	}
+
+	if string(target.Get(byteKey)) != baseValue {
+		t.Errorf("The key %s should be restored, got %s", byteKey, target.Get(byteKey))
+	}
+}
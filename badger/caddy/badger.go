@@ -3,6 +3,7 @@
 package caddy
 
 import (
+	"io"
 	"net/http"
 
 	"github.com/caddyserver/caddy/v2"
@@ -17,6 +18,8 @@ const moduleName = "badger"
 type Badger struct {
 	// Keep the handler configuration.
 	core.Configuration
+
+	storer core.Storer
 }
 
 //nolint:gochecknoinits
@@ -41,11 +44,21 @@ func (b *Badger) Provision(ctx caddy.Context) error {
 		return err
 	}
 
+	b.storer = storer
 	core.RegisterStorage(storer)
 
 	return nil
 }
 
+// Cleanup releases the badger instance when the module is unloaded.
+func (b *Badger) Cleanup() error {
+	if closer, ok := b.storer.(io.Closer); ok {
+		return closer.Close()
+	}
+
+	return nil
+}
+
 func (b *Badger) ServeHTTP(rw http.ResponseWriter, rq *http.Request, next caddyhttp.Handler) error {
 	return next.ServeHTTP(rw, rq)
 }
@@ -53,5 +66,6 @@ func (b *Badger) ServeHTTP(rw http.ResponseWriter, rq *http.Request, next caddyh
 // Interface guards.
 var (
 	_ caddy.Provisioner           = (*Badger)(nil)
+	_ caddy.CleanerUpper          = (*Badger)(nil)
 	_ caddyhttp.MiddlewareHandler = (*Badger)(nil)
 )
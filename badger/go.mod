@@ -6,6 +6,7 @@ require (
 	dario.cat/mergo v1.0.1
 	github.com/darkweak/storages/core v0.0.15
 	github.com/dgraph-io/badger/v3 v3.2103.5
+	github.com/dustin/go-humanize v1.0.1
 	github.com/pierrec/lz4/v4 v4.1.22
 	go.uber.org/zap v1.27.0
 )
@@ -14,7 +15,6 @@ require (
 	github.com/cespare/xxhash v1.1.0 // indirect
 	github.com/cespare/xxhash/v2 v2.1.1 // indirect
 	github.com/dgraph-io/ristretto v0.1.1 // indirect
-	github.com/dustin/go-humanize v1.0.0 // indirect
 	github.com/gogo/protobuf v1.3.2 // indirect
 	github.com/golang/glog v0.0.0-20160126235308-23def4e6c14b // indirect
 	github.com/golang/groupcache v0.0.0-20190702054246-869f871628b6 // indirect
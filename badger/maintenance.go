@@ -0,0 +1,248 @@
+//go:build !wasm && !wasi
+
+package badger
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/dgraph-io/badger/v3"
+	"github.com/dustin/go-humanize"
+)
+
+const (
+	defaultValueLogGCInterval     = 10 * time.Minute
+	defaultValueLogGCDiscardRatio = 0.5
+)
+
+// MaintenanceReport summarizes what the background maintenance reclaimed since the provider started.
+type MaintenanceReport struct {
+	// Number of maintenance runs.
+	Runs int64
+	// Number of value log files rewritten by the value log GC.
+	RewrittenFiles int64
+	// Value log bytes reclaimed on disk.
+	ReclaimedBytes int64
+	// Number of LSM tree flattens done while the cache was idle.
+	Flattens int64
+	LastRun  time.Time
+}
+
+type maintenanceConfiguration struct {
+	interval      time.Duration
+	discardRatio  float64
+	flattenOnIdle bool
+}
+
+type maintenance struct {
+	maintenanceConfiguration
+
+	stop     chan struct{}
+	done     chan struct{}
+	stopOnce sync.Once
+
+	mu     sync.Mutex
+	report MaintenanceReport
+}
+
+func parseMaintenanceConfiguration(configuration interface{}) maintenanceConfiguration {
+	cfg := maintenanceConfiguration{
+		interval:     defaultValueLogGCInterval,
+		discardRatio: defaultValueLogGCDiscardRatio,
+	}
+
+	configMap, ok := configuration.(map[string]interface{})
+	if !ok {
+		return cfg
+	}
+
+	if v := configMap["ValueLogGCInterval"]; v != nil {
+		switch val := v.(type) {
+		case time.Duration:
+			cfg.interval = val
+		case float64:
+			cfg.interval = time.Duration(val)
+		case string:
+			if d, err := time.ParseDuration(val); err == nil {
+				cfg.interval = d
+			}
+		}
+	}
+
+	if v := configMap["ValueLogGCDiscardRatio"]; v != nil {
+		switch val := v.(type) {
+		case float64:
+			cfg.discardRatio = val
+		case string:
+			if f, err := strconv.ParseFloat(val, 64); err == nil {
+				cfg.discardRatio = f
+			}
+		}
+	}
+
+	if v := configMap["FlattenOnIdle"]; v != nil {
+		if b, ok := v.(bool); ok {
+			cfg.flattenOnIdle = b
+		} else if s, ok := v.(string); ok {
+			cfg.flattenOnIdle, _ = strconv.ParseBool(s)
+		}
+	}
+
+	return cfg
+}
+
+// startMaintenance runs the value log GC periodically, it's a no-op for the in-memory databases
+// or when the interval is not positive.
+func (provider *Badger) startMaintenance() {
+	if provider.maintenance == nil || provider.maintenance.interval <= 0 || provider.DB.Opts().InMemory {
+		return
+	}
+
+	provider.maintenance.stop = make(chan struct{})
+	provider.maintenance.done = make(chan struct{})
+	provider.maintenance.stopOnce = sync.Once{}
+
+	go provider.maintenanceLoop(provider.maintenance.stop, provider.maintenance.done)
+}
+
+// stopMaintenance stops the maintenance loop and waits for the current run to finish.
+// It returns true if the loop was running.
+func (provider *Badger) stopMaintenance() bool {
+	if provider.maintenance == nil || provider.maintenance.stop == nil {
+		return false
+	}
+
+	stopped := false
+
+	provider.maintenance.stopOnce.Do(func() {
+		close(provider.maintenance.stop)
+		<-provider.maintenance.done
+
+		stopped = true
+	})
+
+	return stopped
+}
+
+func (provider *Badger) maintenanceLoop(stop, done chan struct{}) {
+	defer close(done)
+
+	ticker := time.NewTicker(provider.maintenance.interval)
+	defer ticker.Stop()
+
+	lastWrites := provider.writes.Load()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			writes := provider.writes.Load()
+			provider.runMaintenance(writes == lastWrites, stop)
+			lastWrites = writes
+		}
+	}
+}
+
+// valueLogSize sums the value log files on disk. DB.Size is only refreshed
+// every minute by badger, it can't tell what a run reclaimed.
+func (provider *Badger) valueLogSize() int64 {
+	files, err := filepath.Glob(filepath.Join(provider.DB.Opts().ValueDir, "*.vlog"))
+	if err != nil {
+		return 0
+	}
+
+	size := int64(0)
+
+	for _, file := range files {
+		if info, err := os.Stat(file); err == nil {
+			size += info.Size()
+		}
+	}
+
+	return size
+}
+
+func (provider *Badger) runMaintenance(idle bool, stop chan struct{}) {
+	vlogBefore := provider.valueLogSize()
+	rewritten := int64(0)
+
+	// Each successful call rewrites one value log file, loop until there is nothing left to reclaim.
+loop:
+	for {
+		select {
+		case <-stop:
+			break loop
+		default:
+		}
+
+		err := provider.DB.RunValueLogGC(provider.maintenance.discardRatio)
+		if err != nil {
+			if !errors.Is(err, badger.ErrNoRewrite) && !errors.Is(err, badger.ErrRejected) {
+				provider.logger.Errorf("Impossible to run the Badger value log GC, %v", err)
+			}
+
+			break
+		}
+
+		rewritten++
+	}
+
+	flattened := int64(0)
+
+	if idle && provider.maintenance.flattenOnIdle {
+		if err := provider.DB.Flatten(1); err != nil {
+			provider.logger.Errorf("Impossible to flatten the Badger LSM tree, %v", err)
+		} else {
+			flattened = 1
+		}
+	}
+
+	vlogAfter := provider.valueLogSize()
+	reclaimed := max(vlogBefore-vlogAfter, 0)
+
+	provider.maintenance.mu.Lock()
+	provider.maintenance.report.Runs++
+	provider.maintenance.report.RewrittenFiles += rewritten
+	provider.maintenance.report.ReclaimedBytes += reclaimed
+	provider.maintenance.report.Flattens += flattened
+	provider.maintenance.report.LastRun = time.Now()
+	provider.maintenance.mu.Unlock()
+
+	//nolint:gosec
+	provider.logger.Infof(
+		"Badger maintenance rewrote %d value log files, reclaimed %s, flattened: %t",
+		rewritten,
+		humanize.IBytes(uint64(reclaimed)),
+		flattened > 0,
+	)
+}
+
+// MaintenanceReport returns what the background maintenance reclaimed so far.
+func (provider *Badger) MaintenanceReport() MaintenanceReport {
+	if provider.maintenance == nil {
+		return MaintenanceReport{}
+	}
+
+	provider.maintenance.mu.Lock()
+	defer provider.maintenance.mu.Unlock()
+
+	return provider.maintenance.report
+}
+
+// String returns a human readable report.
+func (r MaintenanceReport) String() string {
+	//nolint:gosec
+	return fmt.Sprintf(
+		"%d runs, %d value log files rewritten, %s reclaimed, %d flattens",
+		r.Runs,
+		r.RewrittenFiles,
+		humanize.IBytes(uint64(r.ReclaimedBytes)),
+		r.Flattens,
+	)
+}
@@ -0,0 +1,12 @@
+package core
+
+import "io"
+
+// BackupStorer is implemented by the storers able to dump their content to a
+// stream and to load it back while they are running, e.g. to warm a new node.
+type BackupStorer interface {
+	// Backup writes every live entry to the writer.
+	Backup(w io.Writer) error
+	// Restore loads the entries written by Backup, the existing ones are kept.
+	Restore(r io.Reader) error
+}
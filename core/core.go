@@ -20,6 +20,7 @@ type Storer interface {
 	Get(key string) []byte
 	Set(key string, value []byte, duration time.Duration) error
 	Delete(key string)
+	// DeleteMany deletes the keys selected by the pattern, see ParseMatcher.
 	DeleteMany(key string)
 	Init() error
 	Name() string
@@ -55,6 +56,26 @@ func DecodeMapping(item []byte) (*StorageMapper, error) {
 }
 
 func MappingElection(provider Storer, item []byte, req *http.Request, validator *Revalidator, logger Logger) (resultFresh *http.Response, resultStale *http.Response, e error) {
+	return MappingElectionWithLoader(item, req, validator, logger, func(key string, req *http.Request) (*http.Response, error) {
+		response := provider.Get(key)
+		if response == nil {
+			return nil, nil
+		}
+
+		bufW := new(bytes.Buffer)
+		reader := lz4.NewReader(bytes.NewBuffer(response))
+		_, _ = reader.WriteTo(bufW)
+
+		return http.ReadResponse(bufio.NewReader(bufW), req)
+	})
+}
+
+// ResponseLoader returns the response stored under the key, or a nil response if it doesn't exist.
+type ResponseLoader func(key string, req *http.Request) (*http.Response, error)
+
+// MappingElectionWithLoader elects the fresh and stale candidates from the mapping
+// and loads them using the given loader.
+func MappingElectionWithLoader(item []byte, req *http.Request, validator *Revalidator, logger Logger, load ResponseLoader) (resultFresh *http.Response, resultStale *http.Response, e error) {
 	mapping := &StorageMapper{}
 
 	if len(item) != 0 {
@@ -86,17 +107,15 @@ func MappingElection(provider Storer, item []byte, req *http.Request, validator
 		if validator.Matched {
 			// If the key is fresh enough.
 			if time.Since(keyItem.GetFreshTime().AsTime()) < 0 {
-				response := provider.Get(keyName)
-				if response != nil {
-					bufW := new(bytes.Buffer)
-					reader := lz4.NewReader(bytes.NewBuffer(response))
-					_, _ = reader.WriteTo(bufW)
+				response, err := load(keyName, req)
+				if err != nil {
+					logger.Errorf("An error occurred while reading response for the key %s: %v", keyName, err)
 
-					if resultFresh, e = http.ReadResponse(bufio.NewReader(bufW), req); e != nil {
-						logger.Errorf("An error occurred while reading response for the key %s: %v", keyName, e)
+					return resultFresh, resultStale, err
+				}
 
-						return resultFresh, resultStale, e
-					}
+				if response != nil {
+					resultFresh = response
 
 					logger.Debugf("The stored key %s matched the current iteration key ETag %+v", keyName, validator)
 
@@ -106,18 +125,20 @@ func MappingElection(provider Storer, item []byte, req *http.Request, validator
 
 			// If the key is still stale.
 			if time.Since(keyItem.GetStaleTime().AsTime()) < 0 {
-				response := provider.Get(keyName)
-				if response != nil {
-					bufW := new(bytes.Buffer)
-					reader := lz4.NewReader(bytes.NewBuffer(response))
-					_, _ = reader.WriteTo(bufW)
+				response, err := load(keyName, req)
+				if err != nil {
+					logger.Errorf("An error occurred while reading response for the key %s: %v", keyName, err)
 
-					if resultStale, e = http.ReadResponse(bufio.NewReader(bufW), req); e != nil {
-						logger.Errorf("An error occurred while reading response for the key %s: %v", keyName, e)
+					return resultFresh, resultStale, err
+				}
 
-						return resultFresh, resultStale, e
+				if response != nil {
+					if resultStale != nil && resultStale.Body != nil {
+						_ = resultStale.Body.Close()
 					}
 
+					resultStale = response
+
 					logger.Debugf("The stored key %s matched the current iteration key ETag %+v as stale", keyName, validator)
 				}
 			}
@@ -1 +1,200 @@
 package core_test
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/darkweak/storages/core"
+	"go.uber.org/zap"
+)
+
+func TestLiteralPrefix(t *testing.T) {
+	for pattern, expected := range map[string]struct {
+		prefix string
+		exact  bool
+	}{
+		"^GET-http-example\\.com-":     {"GET-http-example.com-", true},
+		"^GET-http-example\\.com-.*":   {"GET-http-example.com-", true},
+		"^GET-http-example\\.com-/a$":  {"GET-http-example.com-/a", false},
+		"^GET-http-example\\.com-/[a]": {"GET-http-example.com-/a", true},
+		"^GET-.*-/a":                   {"GET-", false},
+		"GET-http-example\\.com-":      {"", false},
+		"^(?i)GET":                     {"", false},
+		"^a|^b":                        {"", false},
+		"^GET-example.com":             {"GET-example", false},
+		"[":                            {"", false},
+	} {
+		prefix, exact := core.LiteralPrefix(pattern)
+		if prefix != expected.prefix || exact != expected.exact {
+			t.Errorf("The pattern %s should return (%s, %t), got (%s, %t)", pattern, expected.prefix, expected.exact, prefix, exact)
+		}
+	}
+}
+
+func TestClusterSlot(t *testing.T) {
+	for key, expected := range map[string]uint16{
+		"foo":                  12182,
+		"123456789":            12739,
+		"{user1000}.following": core.ClusterSlot("user1000"),
+		"foo{{bar}}zap":        core.ClusterSlot("{bar"),
+	} {
+		if slot := core.ClusterSlot(key); slot != expected {
+			t.Errorf("The key %s should be in the slot %d, got %d", key, expected, slot)
+		}
+	}
+
+	batches := core.GroupBySlot([]string{"{a}1", "{b}1", "{a}2", "{a}3"}, 2)
+	if len(batches) != 3 || len(batches[0]) != 2 || len(batches[1]) != 1 || len(batches[2]) != 1 {
+		t.Errorf("The keys should be grouped by slot in batches of 2, got %v", batches)
+	}
+}
+
+func TestParseMatcher(t *testing.T) {
+	for pattern, expected := range map[string]struct {
+		matched   []string
+		unmatched []string
+		prefix    string
+		exact     bool
+		glob      string
+	}{
+		"GET-.*-/a$": {
+			matched:   []string{"GET-http-a.com-/a", "IDX_GET-http-a.com-/a"},
+			unmatched: []string{"GET-http-a.com-/ab"},
+			glob:      "*",
+		},
+		"^GET-http-a\\.com-": {
+			matched:   []string{"GET-http-a.com-/"},
+			unmatched: []string{"GET-http-axcom-/", "IDX_GET-http-a.com-/"},
+			prefix:    "GET-http-a.com-",
+			exact:     true,
+			glob:      "GET-http-a.com-*",
+		},
+		"prefix:GET-[a].com*": {
+			matched:   []string{"GET-[a].com*/"},
+			unmatched: []string{"GET-a.com-/"},
+			prefix:    "GET-[a].com*",
+			exact:     true,
+			glob:      "GET-\\[a\\].com\\**",
+		},
+		"glob:GET-http-*.com-/[a-c]?": {
+			matched:   []string{"GET-http-b.com-/bx", "GET-http-a.com-/a\n"},
+			unmatched: []string{"GET-http-b.com-/dx", "GET-http-b.com-/a", "IDX_GET-http-b.com-/ax"},
+			prefix:    "GET-http-",
+			glob:      "GET-http-*.com-/[a-c]?",
+		},
+		"glob:GET-café-[éè]*": {
+			matched:   []string{"GET-café-é1", "GET-café-è"},
+			unmatched: []string{"GET-cafe-é1", "GET-café-e1"},
+			prefix:    "GET-café-",
+			glob:      "GET-café-[éè]*",
+		},
+		"glob:GET-\\*-*": {
+			matched:   []string{"GET-*-/"},
+			unmatched: []string{"GET-a-/"},
+			prefix:    "GET-*-",
+			exact:     true,
+			glob:      "GET-\\*-*",
+		},
+	} {
+		matcher, err := core.ParseMatcher(pattern)
+		if err != nil {
+			t.Errorf("The pattern %s should be valid: %v", pattern, err)
+
+			continue
+		}
+
+		for _, key := range expected.matched {
+			if !matcher.Match(key) {
+				t.Errorf("The pattern %s should match %q", pattern, key)
+			}
+		}
+
+		for _, key := range expected.unmatched {
+			if matcher.Match(key) {
+				t.Errorf("The pattern %s shouldn't match %q", pattern, key)
+			}
+		}
+
+		if prefix, exact := matcher.Prefix(); prefix != expected.prefix || exact != expected.exact {
+			t.Errorf("The pattern %s should have the prefix (%s, %t), got (%s, %t)", pattern, expected.prefix, expected.exact, prefix, exact)
+		}
+
+		if glob := matcher.Glob(); glob != expected.glob {
+			t.Errorf("The pattern %s should be pushed down as %s, got %s", pattern, expected.glob, glob)
+		}
+	}
+
+	for _, pattern := range []string{"glob:GET-[a", "["} {
+		if _, err := core.ParseMatcher(pattern); err == nil {
+			t.Errorf("The pattern %s should be invalid", pattern)
+		}
+	}
+}
+
+func TestSupervisor(t *testing.T) {
+	var dials atomic.Int32
+
+	released := make(chan int, 2)
+	supervisor := core.NewSupervisor(0, func() (int, error) {
+		if dials.Add(1) < 3 {
+			return 0, errors.New("connection refused")
+		}
+
+		return 1, nil
+	}, func(client int) {
+		released <- client
+	}, zap.NewNop().Sugar())
+	supervisor.MinBackoff = time.Millisecond
+	supervisor.MaxBackoff = 4 * time.Millisecond
+
+	var mu sync.Mutex
+
+	states := []core.ConnectionState{}
+	connected := make(chan struct{})
+
+	supervisor.OnStateChange(func(state core.ConnectionState) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		states = append(states, state)
+		if state == core.Connected {
+			close(connected)
+		}
+	})
+
+	// The concurrent calls share the same reconnection.
+	for range 10 {
+		go supervisor.Reconnect()
+	}
+
+	select {
+	case <-connected:
+	case <-time.After(time.Second):
+		t.Fatal("The supervisor should reconnect")
+	}
+
+	if dials.Load() != 3 || supervisor.Client() != 1 || supervisor.Reconnecting() {
+		t.Errorf("The supervisor should dial 3 times once, got %d dials and the client %d", dials.Load(), supervisor.Client())
+	}
+
+	if previous := <-released; previous != 0 {
+		t.Errorf("The replaced client should be released, got %d", previous)
+	}
+
+	supervisor.Close()
+	supervisor.Reconnect()
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	if len(states) != 3 || states[0] != core.Reconnecting || states[1] != core.Connected || states[2] != core.Closed {
+		t.Errorf("The states should be reconnecting, connected then closed, got %v", states)
+	}
+
+	if supervisor.Reconnecting() || dials.Load() != 3 {
+		t.Error("The closed supervisor shouldn't reconnect")
+	}
+}
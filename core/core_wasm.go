@@ -20,6 +20,7 @@ type Storer interface {
 	Get(key string) []byte
 	Set(key string, value []byte, duration time.Duration) error
 	Delete(key string)
+	// DeleteMany deletes the keys selected by the pattern, see ParseMatcher.
 	DeleteMany(key string)
 	Init() error
 	Name() string
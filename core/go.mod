@@ -4,5 +4,8 @@ go 1.22.1
 
 require (
 	github.com/pierrec/lz4/v4 v4.1.22
+	go.uber.org/zap v1.27.0
 	google.golang.org/protobuf v1.36.5
 )
+
+require go.uber.org/multierr v1.10.0 // indirect
@@ -0,0 +1,269 @@
+package core
+
+import (
+	"errors"
+	"regexp"
+	"regexp/syntax"
+	"strings"
+)
+
+// ErrEmptyPrefix is returned by DeletePrefix instead of deleting every key.
+var ErrEmptyPrefix = errors.New("the prefix to delete is empty")
+
+// PrefixDeleter is implemented by the storers able to delete every key
+// starting with a prefix without scanning the whole keyspace. An empty
+// prefix is rejected with ErrEmptyPrefix.
+type PrefixDeleter interface {
+	DeletePrefix(prefix string) error
+}
+
+// LiteralPrefix returns the literal prefix every key matched by the regex
+// pattern starts with. It's only set when the pattern is anchored with ^.
+// Exact is true when starting with the prefix is enough to match the pattern
+// (e.g. ^prefix or ^prefix.*), so the deletion can be delegated to the backend.
+func LiteralPrefix(pattern string) (prefix string, exact bool) {
+	re, err := syntax.Parse(pattern, syntax.Perl)
+	if err != nil {
+		return "", false
+	}
+
+	re = re.Simplify()
+
+	subs := []*syntax.Regexp{re}
+	if re.Op == syntax.OpConcat {
+		subs = re.Sub
+	}
+
+	if len(subs) == 0 || subs[0].Op != syntax.OpBeginText {
+		return "", false
+	}
+
+	subs = subs[1:]
+
+	var builder strings.Builder
+
+	for len(subs) > 0 && subs[0].Op == syntax.OpLiteral && subs[0].Flags&syntax.FoldCase == 0 {
+		builder.WriteString(string(subs[0].Rune))
+
+		subs = subs[1:]
+	}
+
+	switch {
+	case len(subs) == 0:
+		exact = true
+	case len(subs) == 1 && subs[0].Op == syntax.OpStar:
+		// The stored keys don't contain new lines, .* matches any suffix.
+		op := subs[0].Sub[0].Op
+		exact = op == syntax.OpAnyChar || op == syntax.OpAnyCharNotNL
+	}
+
+	return builder.String(), exact
+}
+
+// MatcherKind is the syntax of a DeleteMany pattern.
+type MatcherKind int
+
+const (
+	// RegexMatcher patterns are Go regular expressions, it's the default kind.
+	RegexMatcher MatcherKind = iota
+	// PrefixMatcher patterns match every key starting with them.
+	PrefixMatcher
+	// GlobMatcher patterns use the Redis glob syntax (*, ?, [...] and \ escapes).
+	GlobMatcher
+)
+
+const (
+	prefixScheme = "prefix:"
+	globScheme   = "glob:"
+	regexScheme  = "regex:"
+)
+
+var errUnterminatedClass = errors.New("unterminated [ in glob pattern")
+
+// Matcher selects the keys deleted by DeleteMany, every storer honours the
+// same semantics whatever its backend.
+type Matcher struct {
+	Kind    MatcherKind
+	Pattern string
+	re      *regexp.Regexp
+}
+
+// NewMatcher compiles the pattern with the given kind.
+func NewMatcher(kind MatcherKind, pattern string) (Matcher, error) {
+	source := pattern
+
+	switch kind {
+	case PrefixMatcher:
+		source = "^" + regexp.QuoteMeta(pattern)
+	case GlobMatcher:
+		var err error
+		if source, err = globToRegexp(pattern); err != nil {
+			return Matcher{}, err
+		}
+	case RegexMatcher:
+	}
+
+	re, err := regexp.Compile(source)
+	if err != nil {
+		return Matcher{}, err
+	}
+
+	return Matcher{Kind: kind, Pattern: pattern, re: re}, nil
+}
+
+// ParseMatcher parses the DeleteMany argument. The pattern may be prefixed by
+// its kind (prefix:, glob: or regex:), it's a regular expression otherwise.
+func ParseMatcher(pattern string) (Matcher, error) {
+	switch {
+	case strings.HasPrefix(pattern, prefixScheme):
+		return NewMatcher(PrefixMatcher, strings.TrimPrefix(pattern, prefixScheme))
+	case strings.HasPrefix(pattern, globScheme):
+		return NewMatcher(GlobMatcher, strings.TrimPrefix(pattern, globScheme))
+	default:
+		return NewMatcher(RegexMatcher, strings.TrimPrefix(pattern, regexScheme))
+	}
+}
+
+// String returns the pattern prefixed by its kind, ParseMatcher reverses it.
+func (m Matcher) String() string {
+	switch m.Kind {
+	case PrefixMatcher:
+		return prefixScheme + m.Pattern
+	case GlobMatcher:
+		return globScheme + m.Pattern
+	default:
+		return regexScheme + m.Pattern
+	}
+}
+
+// Match returns true if the key is selected by the matcher.
+func (m Matcher) Match(key string) bool {
+	return m.re != nil && m.re.MatchString(key)
+}
+
+// Regexp returns the regular expression equivalent to the matcher.
+func (m Matcher) Regexp() *regexp.Regexp {
+	return m.re
+}
+
+// Prefix returns the literal prefix every matched key starts with, exact is
+// true when starting with the prefix is enough to be matched.
+func (m Matcher) Prefix() (prefix string, exact bool) {
+	switch m.Kind {
+	case PrefixMatcher:
+		return m.Pattern, true
+	case GlobMatcher:
+		return globPrefix(m.Pattern)
+	default:
+		return LiteralPrefix(m.Pattern)
+	}
+}
+
+// Glob returns a Redis glob pattern matching at least every matched key, the
+// keys it returns must still be filtered with Match unless Kind is GlobMatcher.
+func (m Matcher) Glob() string {
+	if m.Kind == GlobMatcher {
+		return m.Pattern
+	}
+
+	prefix, _ := m.Prefix()
+
+	return escapeGlob(prefix) + "*"
+}
+
+// globToRegexp converts a Redis glob pattern to an anchored regular expression.
+func globToRegexp(pattern string) (string, error) {
+	var builder strings.Builder
+
+	builder.WriteString("(?s)^")
+
+	for i := 0; i < len(pattern); i++ {
+		switch c := pattern[i]; c {
+		case '*':
+			builder.WriteString(".*")
+		case '?':
+			builder.WriteString(".")
+		case '\\':
+			if i+1 < len(pattern) {
+				i++
+			}
+
+			builder.WriteString(regexp.QuoteMeta(pattern[i : i+1]))
+		case '[':
+			end := strings.IndexByte(pattern[i+1:], ']')
+			if end < 0 {
+				return "", errUnterminatedClass
+			}
+
+			class := pattern[i+1 : i+1+end]
+			i += end + 1
+
+			builder.WriteString("[")
+
+			if strings.HasPrefix(class, "^") {
+				builder.WriteString("^")
+
+				class = class[1:]
+			}
+
+			for j := 0; j < len(class); j++ {
+				if class[j] == '-' && j > 0 && j < len(class)-1 {
+					builder.WriteString("-")
+
+					continue
+				}
+
+				if class[j] == '\\' && j+1 < len(class) {
+					j++
+				}
+
+				builder.WriteString(regexp.QuoteMeta(class[j : j+1]))
+			}
+
+			builder.WriteString("]")
+		default:
+			builder.WriteString(regexp.QuoteMeta(pattern[i : i+1]))
+		}
+	}
+
+	builder.WriteString("$")
+
+	return builder.String(), nil
+}
+
+// globPrefix returns the literal characters before the first glob wildcard.
+func globPrefix(pattern string) (prefix string, exact bool) {
+	var builder strings.Builder
+
+	for i := 0; i < len(pattern); i++ {
+		switch c := pattern[i]; c {
+		case '*', '?', '[':
+			return builder.String(), pattern[i:] == "*"
+		case '\\':
+			if i+1 < len(pattern) {
+				i++
+			}
+
+			builder.WriteByte(pattern[i])
+		default:
+			builder.WriteByte(c)
+		}
+	}
+
+	return builder.String(), false
+}
+
+// escapeGlob escapes the Redis glob special characters.
+func escapeGlob(literal string) string {
+	var builder strings.Builder
+
+	for i := 0; i < len(literal); i++ {
+		if strings.IndexByte(`*?[]\`, literal[i]) >= 0 {
+			builder.WriteByte('\\')
+		}
+
+		builder.WriteByte(literal[i])
+	}
+
+	return builder.String()
+}
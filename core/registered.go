@@ -7,9 +7,19 @@ import (
 
 var registered = sync.Map{}
 
+func storerKey(s Storer) string {
+	return fmt.Sprintf("%s-%s", s.Name(), s.Uuid())
+}
+
 func RegisterStorage(s Storer) {
 	_ = s.Init()
-	registered.Store(fmt.Sprintf("%s-%s", s.Name(), s.Uuid()), s)
+	registered.Store(storerKey(s), s)
+}
+
+// UnregisterStorage removes the storer from the registered ones, the storers
+// call it once closed so they are not handed out anymore.
+func UnregisterStorage(s Storer) {
+	registered.Delete(storerKey(s))
 }
 
 func GetRegisteredStorer(name string) Storer {
@@ -0,0 +1,139 @@
+package core
+
+// MappingMergeScript is a Redis Lua script storing a body and merging its
+// entry into the mapping in a single atomic round trip.
+//
+// KEYS[1] is the mapping key, KEYS[2] the optional body key.
+// ARGV[1] is the new entry encoded as a single entry StorageMapper (see
+// MappingUpdater), ARGV[2] the entry key, ARGV[3] the current unix time in
+// milliseconds, ARGV[4] the body and ARGV[5] its TTL in milliseconds.
+//
+// The StorageMapper map entries are concatenated protobuf fields, the script
+// drops the previous entry for the key and the entries past their stale time
+// without decoding the rest. The mapping expires with its furthest stale time.
+const MappingMergeScript = `
+local function varint(s, pos)
+	local result, multiplier = 0, 1
+	while true do
+		local b = string.byte(s, pos)
+		if b == nil then
+			return nil, pos
+		end
+		pos = pos + 1
+		result = result + (b % 128) * multiplier
+		if b < 128 then
+			return result, pos
+		end
+		multiplier = multiplier * 128
+	end
+end
+
+-- Calls visit(field, value) for the varint fields and visit(field, from, to, start)
+-- for the length delimited ones, returns false if the message is malformed.
+local function fields(s, from, to, visit)
+	local pos = from
+	while pos <= to do
+		local start = pos
+		local tag, length
+		tag, pos = varint(s, pos)
+		if tag == nil then
+			return false
+		end
+		local field, wire = math.floor(tag / 8), tag % 8
+		if wire == 0 then
+			length, pos = varint(s, pos)
+			if length == nil then
+				return false
+			end
+			visit(field, length)
+		elseif wire == 2 then
+			length, pos = varint(s, pos)
+			if length == nil or pos + length - 1 > to then
+				return false
+			end
+			visit(field, pos, pos + length - 1, start)
+			pos = pos + length
+		elseif wire == 1 then
+			pos = pos + 8
+		elseif wire == 5 then
+			pos = pos + 4
+		else
+			return false
+		end
+	end
+	return pos == to + 1
+end
+
+-- Returns the mapping entries with their key and stale time in milliseconds.
+local function entries(s)
+	local result = {}
+	local valid = fields(s, 1, #s, function(field, from, to, start)
+		if field ~= 1 or to == nil then
+			return
+		end
+		local entry = {raw = string.sub(s, start, to), stale = 0}
+		fields(s, from, to, function(f, a, b)
+			if b == nil then
+				return
+			end
+			if f == 1 then
+				entry.key = string.sub(s, a, b)
+			elseif f == 2 then
+				fields(s, a, b, function(kf, ka, kb)
+					if kf == 3 and kb ~= nil then
+						local seconds, nanos = 0, 0
+						fields(s, ka, kb, function(tf, tv)
+							if tf == 1 then
+								seconds = tv
+							elseif tf == 2 then
+								nanos = tv
+							end
+						end)
+						entry.stale = seconds * 1000 + math.floor(nanos / 1000000)
+					end
+				end)
+			end
+		end)
+		table.insert(result, entry)
+	end)
+	if not valid then
+		return {}
+	end
+	return result
+end
+
+local now = tonumber(ARGV[3])
+
+if KEYS[2] ~= nil then
+	local ttl = tonumber(ARGV[5])
+	if ttl > 0 then
+		redis.call('SET', KEYS[2], ARGV[4], 'PX', ttl)
+	else
+		redis.call('SET', KEYS[2], ARGV[4])
+	end
+end
+
+local merged, furthest = {}, 0
+local current = redis.call('GET', KEYS[1])
+if current then
+	for _, entry in ipairs(entries(current)) do
+		if entry.key ~= ARGV[2] and entry.stale > now then
+			table.insert(merged, entry.raw)
+			furthest = math.max(furthest, entry.stale)
+		end
+	end
+end
+
+for _, entry in ipairs(entries(ARGV[1])) do
+	table.insert(merged, entry.raw)
+	furthest = math.max(furthest, entry.stale)
+end
+
+if furthest <= now then
+	redis.call('DEL', KEYS[1])
+	return 0
+end
+
+redis.call('SET', KEYS[1], table.concat(merged), 'PX', furthest - now)
+return furthest - now
+`
@@ -0,0 +1,67 @@
+package core
+
+import "strings"
+
+// Number of hash slots in a Redis Cluster.
+const clusterSlots = 16384
+
+// ClusterSlot returns the Redis Cluster hash slot of the key. Only the hash
+// tag is hashed when the key contains a non-empty {...} section.
+func ClusterSlot(key string) uint16 {
+	if start := strings.IndexByte(key, '{'); start >= 0 {
+		if end := strings.IndexByte(key[start+1:], '}'); end > 0 {
+			key = key[start+1 : start+1+end]
+		}
+	}
+
+	return crc16(key) % clusterSlots
+}
+
+// GroupBySlot splits the keys in batches of at most size keys sharing the
+// same Redis Cluster hash slot, so a multi-key command never crosses slots.
+func GroupBySlot(keys []string, size int) [][]string {
+	slots := make(map[uint16][]string)
+	order := []uint16{}
+
+	for _, key := range keys {
+		slot := ClusterSlot(key)
+		if _, found := slots[slot]; !found {
+			order = append(order, slot)
+		}
+
+		slots[slot] = append(slots[slot], key)
+	}
+
+	batches := [][]string{}
+
+	for _, slot := range order {
+		group := slots[slot]
+		for size > 0 && len(group) > size {
+			batches = append(batches, group[:size])
+			group = group[size:]
+		}
+
+		batches = append(batches, group)
+	}
+
+	return batches
+}
+
+// crc16 implements the CRC16-XMODEM checksum used by Redis Cluster.
+func crc16(key string) uint16 {
+	var crc uint16
+
+	for i := 0; i < len(key); i++ {
+		crc ^= uint16(key[i]) << 8
+
+		for range 8 {
+			if crc&0x8000 != 0 {
+				crc = crc<<1 ^ 0x1021
+			} else {
+				crc <<= 1
+			}
+		}
+	}
+
+	return crc
+}
@@ -0,0 +1,35 @@
+package core
+
+// Stats is a snapshot of the statistics collected by a storer.
+type Stats struct {
+	// Number of lookups that found the key.
+	Hits int64 `json:"hits"`
+	// Number of lookups that didn't find the key.
+	Misses int64 `json:"misses"`
+	// Number of entries removed to make room for new ones.
+	Evictions int64 `json:"evictions"`
+	// Number of writes the storer refused.
+	RejectedSets int64 `json:"rejected_sets"`
+	// Current size of the storer, weighted by its cost function.
+	Size int64 `json:"size"`
+}
+
+// StatsStorer is implemented by the storers able to report their statistics.
+type StatsStorer interface {
+	Stats() Stats
+}
+
+// GetRegisteredStats returns the statistics of every registered storer that reports them.
+func GetRegisteredStats() map[string]Stats {
+	stats := make(map[string]Stats)
+
+	registered.Range(func(key, value any) bool {
+		if s, ok := value.(StatsStorer); ok {
+			stats[key.(string)] = s.Stats()
+		}
+
+		return true
+	})
+
+	return stats
+}
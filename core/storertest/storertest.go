@@ -0,0 +1,146 @@
+// Package storertest holds the test helpers and scenarios shared by the
+// storers talking to the same backend (e.g. the redis and go-redis providers).
+package storertest
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/darkweak/storages/core"
+)
+
+const baseValue = "My first data"
+
+// Unreachable fails on CI where the compose services always run, the test is
+// skipped elsewhere.
+func Unreachable(t *testing.T, service string, err error) {
+	t.Helper()
+
+	if os.Getenv("CI") != "" {
+		t.Fatalf("The %s is not reachable: %v", service, err)
+	}
+
+	t.Skipf("The %s is not reachable: %v", service, err)
+}
+
+// EventuallyGet retries the read while the replicas are updated asynchronously.
+func EventuallyGet(client core.Storer, key string) []byte {
+	for range 20 {
+		if value := client.Get(key); len(value) > 0 {
+			return value
+		}
+
+		time.Sleep(50 * time.Millisecond)
+	}
+
+	return nil
+}
+
+// SetMultiLevel checks both variants are merged in the mapping and their bodies stored.
+func SetMultiLevel(t *testing.T, client core.Storer) {
+	t.Helper()
+
+	defer client.DeleteMany(".*")
+
+	for _, variedKey := range []string{"GET-http-domain.com-/first", "GET-http-domain.com-/second"} {
+		if err := client.SetMultiLevel("GET-http-domain.com-/", variedKey, []byte(baseValue), http.Header{}, "", 20*time.Second, variedKey); err != nil {
+			t.Errorf("Impossible to store the key %s: %v", variedKey, err)
+		}
+	}
+
+	mapping, err := core.DecodeMapping(client.Get(core.MappingKeyPrefix + "GET-http-domain.com-/"))
+	if err != nil || len(mapping.GetMapping()) != 2 {
+		t.Errorf("The mapping should contain both variants, got %v: %v", mapping.GetMapping(), err)
+	}
+
+	if len(client.Get("GET-http-domain.com-/first")) == 0 {
+		t.Error("The body should be stored with its mapping")
+	}
+}
+
+// MapKeysBatches checks the keys are read in more than a single batch.
+func MapKeysBatches(t *testing.T, client core.Storer) {
+	t.Helper()
+
+	defer client.DeleteMany("prefix:BATCH_")
+
+	// More keys than a single MGET reads.
+	for i := range 2500 {
+		_ = client.Set(fmt.Sprintf("BATCH_%d", i), []byte(fmt.Sprintf("value %d", i)), 20*time.Second)
+	}
+
+	keys := client.MapKeys("BATCH_")
+	if len(keys) != 2500 {
+		t.Errorf("The map should contain 2500 elements, got %d", len(keys))
+	}
+
+	if keys["1234"] != "value 1234" {
+		t.Errorf("The key BATCH_1234 should be mapped to its value, got %q", keys["1234"])
+	}
+
+	for _, variedKey := range []string{"GET-http-batch.com-/first", "GET-http-batch.com-/second"} {
+		_ = client.SetMultiLevel("GET-http-batch.com-/", variedKey, []byte(baseValue), http.Header{}, "", 20*time.Second, variedKey)
+	}
+
+	defer client.DeleteMany("prefix:" + core.MappingKeyPrefix + "GET-http-batch.com-")
+	defer client.DeleteMany("prefix:GET-http-batch.com-")
+
+	if len(client.ListKeys()) < 2 {
+		t.Error("The mapping keys should be listed")
+	}
+}
+
+// Cluster checks the keys spread over every master are listed and deleted,
+// and the mapping is merged when it doesn't share the slot of the body.
+func Cluster(t *testing.T, client core.Storer) {
+	t.Helper()
+
+	if err := client.Set("CLUSTER_KEY_0", []byte(baseValue), 20*time.Second); err != nil {
+		Unreachable(t, "redis cluster", err)
+	}
+
+	for i := 1; i < 100; i++ {
+		_ = client.Set(fmt.Sprintf("CLUSTER_KEY_%d", i), []byte(baseValue), 20*time.Second)
+	}
+
+	// The keys are spread over every master.
+	if keys := client.MapKeys("CLUSTER_KEY_"); len(keys) != 100 {
+		t.Errorf("The map should contain 100 elements, got %d", len(keys))
+	}
+
+	client.DeleteMany("glob:CLUSTER_KEY_*")
+
+	if keys := client.MapKeys("CLUSTER_KEY_"); len(keys) != 0 {
+		t.Errorf("The map should be empty, got %d elements", len(keys))
+	}
+
+	base := "GET-http-cluster.com-/"
+	variedKey := base + "variant"
+
+	// Without HashTag the mapping and the body live on different slots.
+	if core.ClusterSlot(core.MappingKeyPrefix+base) == core.ClusterSlot(variedKey) {
+		t.Fatal("The mapping and the body keys should be on different slots")
+	}
+
+	response := "HTTP/1.1 200 OK\r\nContent-Length: 13\r\n\r\n" + baseValue
+	if err := client.SetMultiLevel(base, variedKey, []byte(response), http.Header{}, "", 20*time.Second, variedKey); err != nil {
+		t.Fatalf("Impossible to store the key %s in the cluster: %v", variedKey, err)
+	}
+
+	defer client.Delete(core.MappingKeyPrefix + base)
+	defer client.Delete(variedKey)
+
+	fresh, _ := client.GetMultiLevel(base, httptest.NewRequest(http.MethodGet, "/", nil), &core.Revalidator{})
+	if fresh == nil {
+		t.Fatal("The key stored in the cluster should be fresh")
+	}
+
+	if body, _ := io.ReadAll(fresh.Body); string(body) != baseValue {
+		t.Errorf("The body %s doesn't match %s", body, baseValue)
+	}
+}
@@ -0,0 +1,228 @@
+package core
+
+import (
+	"math/rand/v2"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ConnectionState is the state of a supervised connection.
+type ConnectionState int
+
+const (
+	// Connected is the state of a usable client.
+	Connected ConnectionState = iota
+	// Reconnecting is the state while a new client is dialed.
+	Reconnecting
+	// Closed is the final state once the supervisor is closed.
+	Closed
+)
+
+const (
+	defaultMinBackoff = 500 * time.Millisecond
+	defaultMaxBackoff = 30 * time.Second
+)
+
+func (state ConnectionState) String() string {
+	switch state {
+	case Connected:
+		return "connected"
+	case Reconnecting:
+		return "reconnecting"
+	default:
+		return "closed"
+	}
+}
+
+// Supervisor owns the client of a network-backed storer and replaces it when
+// the connection is lost. A single reconnection runs at a time, the dial is
+// retried with an exponential backoff and jitter until it succeeds or the
+// supervisor is closed.
+type Supervisor[T any] struct {
+	// Bounds of the delay between two attempts, set them before the first Reconnect.
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+
+	logger Logger
+	dial   func() (T, error)
+	// Closes the replaced client.
+	release func(T)
+
+	mu        sync.RWMutex
+	client    T
+	state     ConnectionState
+	listeners []func(ConnectionState)
+	closed    bool
+
+	reconnecting atomic.Bool
+	stop         chan struct{}
+	wg           sync.WaitGroup
+}
+
+// NewSupervisor supervises the connected client. Reconnect is a no-op when dial
+// is nil, release may be nil when the replaced clients don't need to be closed.
+func NewSupervisor[T any](client T, dial func() (T, error), release func(T), logger Logger) *Supervisor[T] {
+	return &Supervisor[T]{
+		MinBackoff: defaultMinBackoff,
+		MaxBackoff: defaultMaxBackoff,
+		logger:     logger,
+		dial:       dial,
+		release:    release,
+		client:     client,
+		stop:       make(chan struct{}),
+	}
+}
+
+// Client returns the current client.
+func (s *Supervisor[T]) Client() T {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.client
+}
+
+// State returns the current connection state.
+func (s *Supervisor[T]) State() ConnectionState {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.state
+}
+
+// Reconnecting returns true while a new client is dialed.
+func (s *Supervisor[T]) Reconnecting() bool {
+	return s.reconnecting.Load()
+}
+
+// OnStateChange registers a callback called on each state transition.
+func (s *Supervisor[T]) OnStateChange(listener func(ConnectionState)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.listeners = append(s.listeners, listener)
+}
+
+// Reconnect dials a new client in the background, it returns immediately and
+// the concurrent calls join the reconnection already running.
+func (s *Supervisor[T]) Reconnect() {
+	if s.dial == nil || !s.reconnecting.CompareAndSwap(false, true) {
+		return
+	}
+
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		s.reconnecting.Store(false)
+
+		return
+	}
+
+	s.wg.Add(1)
+	s.mu.Unlock()
+
+	s.setState(Reconnecting)
+
+	go s.reconnect()
+}
+
+// Close stops the running reconnection, the current client is left to the caller.
+func (s *Supervisor[T]) Close() {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+
+		return
+	}
+
+	s.closed = true
+	close(s.stop)
+	s.mu.Unlock()
+
+	s.wg.Wait()
+	s.setState(Closed)
+}
+
+func (s *Supervisor[T]) reconnect() {
+	defer s.wg.Done()
+
+	backoff := s.MinBackoff
+
+	for attempt := 1; ; attempt++ {
+		client, err := s.dial()
+		if err == nil {
+			s.replace(client)
+
+			return
+		}
+
+		delay := jitter(backoff)
+		s.logger.Warnf("Impossible to reconnect (attempt %d), retry in %s: %v", attempt, delay, err)
+
+		select {
+		case <-s.stop:
+			s.reconnecting.Store(false)
+
+			return
+		case <-time.After(delay):
+		}
+
+		backoff = min(2*backoff, s.MaxBackoff)
+	}
+}
+
+func (s *Supervisor[T]) replace(client T) {
+	s.mu.Lock()
+
+	if s.closed {
+		// Closed while dialing, the new client is never used.
+		s.mu.Unlock()
+		s.reconnecting.Store(false)
+
+		if s.release != nil {
+			s.release(client)
+		}
+
+		return
+	}
+
+	previous := s.client
+	s.client = client
+	s.mu.Unlock()
+	s.reconnecting.Store(false)
+
+	if s.release != nil {
+		s.release(previous)
+	}
+
+	s.setState(Connected)
+}
+
+func (s *Supervisor[T]) setState(state ConnectionState) {
+	s.mu.Lock()
+
+	if s.state == state || s.state == Closed {
+		s.mu.Unlock()
+
+		return
+	}
+
+	s.state = state
+	listeners := s.listeners
+	s.mu.Unlock()
+
+	s.logger.Debugf("The connection is now %s", state)
+
+	for _, listener := range listeners {
+		listener(state)
+	}
+}
+
+// jitter returns a random delay between the half and the whole backoff.
+func jitter(backoff time.Duration) time.Duration {
+	if backoff <= 1 {
+		return backoff
+	}
+
+	return backoff/2 + rand.N(backoff/2+1)
+}
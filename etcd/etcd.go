@@ -7,25 +7,29 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
-	"regexp"
 	"strings"
 	"time"
 
 	"github.com/darkweak/storages/core"
 	"github.com/pierrec/lz4/v4"
+	"go.etcd.io/etcd/api/v3/mvccpb"
 	clientv3 "go.etcd.io/etcd/client/v3"
 	"go.uber.org/zap"
-	"google.golang.org/grpc/connectivity"
 )
 
 // Etcd provider type.
 type Etcd struct {
-	*clientv3.Client
-	stale         time.Duration
-	ctx           context.Context
-	logger        core.Logger
-	reconnecting  bool
-	configuration clientv3.Config
+	supervisor *core.Supervisor[*clientv3.Client]
+	stale      time.Duration
+	ctx        context.Context
+	logger     core.Logger
+	nearCache  *nearCache
+	leases     *leasePool
+	health     health
+	// Bounds every call, clientv3 waits for the connection otherwise.
+	timeout time.Duration
+
+	scanConfiguration
 }
 
 // Factory function create new Etcd instance.
@@ -54,26 +58,83 @@ func Factory(etcdCfg core.CacheProvider, logger core.Logger, stale time.Duration
 		}
 	}
 
-	cli, err := clientv3.New(etcdConfiguration)
+	scanCfg := parseScanConfiguration(etcdCfg.Configuration)
+	startup := parseStartupConfiguration(etcdCfg.Configuration)
+
+	nc := parseNearCacheConfiguration(etcdCfg.Configuration)
+	if nc != nil && scanCfg.namespace == "" {
+		logger.Error("Impossible to enable the Etcd near cache.", errNearCacheWithoutNamespace)
+
+		return nil, errNearCacheWithoutNamespace
+	}
+
+	cli, err := newClient(etcdConfiguration, scanCfg.namespace)
 	if err != nil {
 		logger.Error("Impossible to initialize the Etcd DB.", err)
 
 		return nil, err
 	}
 
-	for {
-		if cli.ActiveConnection().GetState() == connectivity.Ready {
-			break
+	provider := &Etcd{
+		ctx:               context.Background(),
+		stale:             stale,
+		logger:            logger,
+		nearCache:         nc,
+		leases:            parseLeasePoolConfiguration(etcdCfg.Configuration),
+		timeout:           etcdConfiguration.DialTimeout,
+		scanConfiguration: scanCfg,
+	}
+	provider.supervisor = core.NewSupervisor(cli, func() (*clientv3.Client, error) {
+		c, err := newClient(etcdConfiguration, scanCfg.namespace)
+		if err != nil {
+			return nil, err
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), startup.timeout)
+		defer cancel()
+
+		if err = waitReady(ctx, c); err != nil {
+			_ = c.Close()
+
+			return nil, err
 		}
+
+		return c, nil
+	}, func(c *clientv3.Client) {
+		_ = c.Close()
+	}, logger)
+	provider.supervisor.OnStateChange(func(state core.ConnectionState) {
+		if state == core.Connected {
+			provider.monitor(provider.Client())
+			// The watch may have missed events while disconnected.
+			provider.nearCache.resync()
+		}
+	})
+	provider.monitor(cli)
+
+	ctx, cancel := context.WithTimeout(context.Background(), startup.timeout)
+	defer cancel()
+
+	if err = waitReady(ctx, cli); err != nil {
+		if !startup.degraded {
+			logger.Errorf("Impossible to connect to the Etcd DB in %s, %v", startup.timeout, err)
+			provider.stopMonitor()
+			_ = cli.Close()
+
+			return nil, err
+		}
+
+		logger.Warnf("Start the Etcd provider degraded until the connection is ready, %v", err)
 	}
 
-	return &Etcd{
-		Client:        cli,
-		ctx:           context.Background(),
-		stale:         stale,
-		logger:        logger,
-		configuration: etcdConfiguration,
-	}, nil
+	provider.startNearCache()
+
+	return provider, nil
+}
+
+// Client returns the current etcd client, it's replaced on reconnection.
+func (provider *Etcd) Client() *clientv3.Client {
+	return provider.supervisor.Client()
 }
 
 // Name returns the storer name.
@@ -83,42 +144,49 @@ func (provider *Etcd) Name() string {
 
 // Uuid returns an unique identifier.
 func (provider *Etcd) Uuid() string {
-	return fmt.Sprintf(
+	uuid := fmt.Sprintf(
 		"%s-%s-%s-%s",
-		strings.Join(provider.Client.Endpoints(), ","),
-		provider.Client.Username,
-		provider.Client.Password,
+		strings.Join(provider.Client().Endpoints(), ","),
+		provider.Client().Username,
+		provider.Client().Password,
 		provider.stale,
 	)
+
+	if provider.namespace != "" {
+		uuid += "-" + provider.namespace
+	}
+
+	return uuid
 }
 
 // ListKeys method returns the list of existing keys.
 func (provider *Etcd) ListKeys() []string {
-	if provider.reconnecting {
+	if provider.supervisor.Reconnecting() {
 		provider.logger.Error("Impossible to list the etcd keys while reconnecting.")
 
 		return []string{}
 	}
 
-	keys := []string{}
-
-	result, e := provider.Client.Get(provider.ctx, core.MappingKeyPrefix, clientv3.WithPrefix())
-
-	if e != nil {
-		if !provider.reconnecting {
-			go provider.Reconnect()
-		}
+	if err := provider.available(); err != nil {
+		provider.logger.Debugf("Impossible to list the etcd keys, %v", err)
 
 		return []string{}
 	}
 
-	for _, k := range result.Kvs {
-		mapping, err := core.DecodeMapping(k.Value)
+	keys := []string{}
+
+	e := provider.scan(core.MappingKeyPrefix, func(kv *mvccpb.KeyValue) {
+		mapping, err := core.DecodeMapping(kv.Value)
 		if err == nil {
 			for _, v := range mapping.GetMapping() {
 				keys = append(keys, v.GetRealKey())
 			}
 		}
+	})
+	if e != nil {
+		provider.Reconnect()
+
+		return []string{}
 	}
 
 	return keys
@@ -126,73 +194,97 @@ func (provider *Etcd) ListKeys() []string {
 
 // MapKeys method returns the map of existing keys.
 func (provider *Etcd) MapKeys(prefix string) map[string]string {
-	if provider.reconnecting {
+	if provider.supervisor.Reconnecting() {
 		provider.logger.Error("Impossible to list the etcd keys while reconnecting.")
 
 		return map[string]string{}
 	}
 
+	if err := provider.available(); err != nil {
+		provider.logger.Debugf("Impossible to list the etcd keys, %v", err)
+
+		return map[string]string{}
+	}
+
 	keys := map[string]string{}
 
-	result, err := provider.Client.Get(provider.ctx, "\x00", clientv3.WithFromKey())
+	err := provider.scan(prefix, func(kv *mvccpb.KeyValue) {
+		nk, _ := strings.CutPrefix(string(kv.Key), prefix)
+		keys[nk] = string(kv.Value)
+	})
 	if err != nil {
-		if !provider.reconnecting {
-			go provider.Reconnect()
-		}
+		provider.Reconnect()
 
 		return map[string]string{}
 	}
 
-	for _, k := range result.Kvs {
-		key := string(k.Key)
-		if strings.HasPrefix(key, prefix) {
-			nk, _ := strings.CutPrefix(key, prefix)
-			keys[nk] = string(k.Value)
-		}
-	}
-
 	return keys
 }
 
 // Get method returns the populated response if exists, empty response then.
 func (provider *Etcd) Get(key string) (item []byte) {
-	if provider.reconnecting {
+	if provider.supervisor.Reconnecting() {
 		provider.logger.Error("Impossible to get the etcd key while reconnecting.")
 
 		return []byte{}
 	}
 
-	result, err := provider.Client.Get(provider.ctx, key)
-	if err != nil && !provider.reconnecting {
-		go provider.Reconnect()
+	if err := provider.available(); err != nil {
+		provider.logger.Debugf("Impossible to get the etcd key, %v", err)
 
-		return
+		return []byte{}
 	}
 
-	if err == nil && result != nil && len(result.Kvs) > 0 {
-		item = result.Kvs[0].Value
+	item, err := provider.get(key)
+	if err != nil {
+		provider.Reconnect()
 	}
 
 	return
 }
 
+// get loads the key from the near cache when enabled, from etcd otherwise.
+func (provider *Etcd) get(key string) ([]byte, error) {
+	if value, found := provider.nearCache.get(key); found {
+		return value, nil
+	}
+
+	ctx, cancel := provider.callContext()
+	defer cancel()
+
+	result, err := provider.Client().Get(ctx, key)
+	if err != nil || result == nil || len(result.Kvs) == 0 {
+		return nil, err
+	}
+
+	provider.nearCache.fill(key, result.Kvs[0].Value, result.Header.Revision)
+
+	return result.Kvs[0].Value, nil
+}
+
 // GetMultiLevel tries to load the key and check if one of linked keys is a fresh/stale candidate.
 func (provider *Etcd) GetMultiLevel(key string, req *http.Request, validator *core.Revalidator) (fresh *http.Response, stale *http.Response) {
-	if provider.reconnecting {
+	if provider.supervisor.Reconnecting() {
 		provider.logger.Error("Impossible to get the etcd key while reconnecting.")
 
 		return
 	}
 
-	result, err := provider.Client.Get(provider.ctx, core.MappingKeyPrefix+key)
+	if err := provider.available(); err != nil {
+		provider.logger.Debugf("Impossible to get the etcd key, %v", err)
+
+		return
+	}
+
+	mapping, err := provider.get(core.MappingKeyPrefix + key)
 	if err != nil {
-		go provider.Reconnect()
+		provider.Reconnect()
 
 		return fresh, stale
 	}
 
-	if len(result.Kvs) > 0 {
-		fresh, stale, _ = core.MappingElection(provider, result.Kvs[0].Value, req, validator, provider.logger)
+	if len(mapping) > 0 {
+		fresh, stale, _ = core.MappingElection(provider, mapping, req, validator, provider.logger)
 	}
 
 	return fresh, stale
@@ -200,7 +292,7 @@ func (provider *Etcd) GetMultiLevel(key string, req *http.Request, validator *co
 
 // SetMultiLevel tries to store the key with the given value and update the mapping key to store metadata.
 func (provider *Etcd) SetMultiLevel(baseKey, variedKey string, value []byte, variedHeaders http.Header, etag string, duration time.Duration, realKey string) error {
-	if provider.reconnecting {
+	if provider.supervisor.Reconnecting() {
 		provider.logger.Error("Impossible to set the etcd value while reconnecting.")
 
 		return errors.New("reconnecting error")
@@ -208,14 +300,14 @@ func (provider *Etcd) SetMultiLevel(baseKey, variedKey string, value []byte, var
 
 	now := time.Now()
 
-	if provider.reconnecting {
+	if provider.supervisor.Reconnecting() {
 		provider.logger.Error("Impossible to set the etcd value while reconnecting.")
 
 		return errors.New("reconnecting error")
 	}
 
-	if provider.Client.ActiveConnection().GetState() != connectivity.Ready && provider.Client.ActiveConnection().GetState() != connectivity.Idle {
-		return fmt.Errorf("the connection is not ready: %v", provider.Client.ActiveConnection().GetState())
+	if err := provider.available(); err != nil {
+		return err
 	}
 
 	compressed := new(bytes.Buffer)
@@ -225,15 +317,9 @@ func (provider *Etcd) SetMultiLevel(baseKey, variedKey string, value []byte, var
 		return err
 	}
 
-	rs, err := provider.Client.Grant(context.TODO(), int64(duration.Seconds()))
-	if err == nil {
-		_, err = provider.Client.Put(provider.ctx, variedKey, compressed.String(), clientv3.WithLease(rs.ID))
-	}
-
-	if err != nil {
-		if !provider.reconnecting {
-			go provider.Reconnect()
-		}
+	// The body is kept during the stale window to remain a stale candidate.
+	if err := provider.put(variedKey, compressed.String(), duration+provider.stale); err != nil {
+		provider.Reconnect()
 
 		provider.logger.Errorf("Impossible to set value into Etcd, %v", err)
 
@@ -253,25 +339,19 @@ func (provider *Etcd) SetMultiLevel(baseKey, variedKey string, value []byte, var
 
 // Set method will store the response in Etcd provider.
 func (provider *Etcd) Set(key string, value []byte, duration time.Duration) error {
-	if provider.reconnecting {
+	if provider.supervisor.Reconnecting() {
 		provider.logger.Error("Impossible to set the etcd value while reconnecting.")
 
 		return errors.New("reconnecting error")
 	}
 
-	if provider.Client.ActiveConnection().GetState() != connectivity.Ready && provider.Client.ActiveConnection().GetState() != connectivity.Idle {
-		return fmt.Errorf("the connection is not ready: %v", provider.Client.ActiveConnection().GetState())
-	}
-
-	rs, err := provider.Client.Grant(context.TODO(), int64(duration.Seconds()))
-	if err == nil {
-		_, err = provider.Client.Put(provider.ctx, key, string(value), clientv3.WithLease(rs.ID))
+	if err := provider.available(); err != nil {
+		return err
 	}
 
+	err := provider.put(key, string(value), duration)
 	if err != nil {
-		if !provider.reconnecting {
-			go provider.Reconnect()
-		}
+		provider.Reconnect()
 
 		provider.logger.Errorf("Impossible to set value into Etcd, %v", err)
 	}
@@ -281,36 +361,46 @@ func (provider *Etcd) Set(key string, value []byte, duration time.Duration) erro
 
 // Delete method will delete the response in Etcd provider if exists corresponding to key param.
 func (provider *Etcd) Delete(key string) {
-	if provider.reconnecting {
+	if provider.supervisor.Reconnecting() {
 		provider.logger.Error("Impossible to delete the etcd key while reconnecting.")
 
 		return
 	}
 
-	_, _ = provider.Client.Delete(provider.ctx, key)
+	if err := provider.available(); err != nil {
+		provider.logger.Debugf("Impossible to delete the etcd key, %v", err)
+
+		return
+	}
+
+	ctx, cancel := provider.callContext()
+	defer cancel()
+
+	_, _ = provider.Client().Delete(ctx, key)
+	provider.nearCache.invalidate(key)
 }
 
 // DeleteMany method will delete the responses in Etcd provider if exists corresponding to the regex key param.
 func (provider *Etcd) DeleteMany(key string) {
-	if provider.reconnecting {
+	if provider.supervisor.Reconnecting() {
 		provider.logger.Error("Impossible to delete the etcd keys while reconnecting.")
 
 		return
 	}
 
-	rgKey, e := regexp.Compile(key)
+	if err := provider.available(); err != nil {
+		provider.logger.Debugf("Impossible to delete the etcd keys, %v", err)
+
+		return
+	}
 
+	matcher, e := core.ParseMatcher(key)
 	if e != nil {
 		return
 	}
 
-	if r, e := provider.Client.Get(provider.ctx, "\x00", clientv3.WithFromKey()); e == nil {
-		for _, k := range r.Kvs {
-			key := string(k.Key)
-			if rgKey.MatchString(key) {
-				provider.Delete(key)
-			}
-		}
+	if e = provider.deleteMatching(matcher); e != nil {
+		provider.logger.Errorf("Impossible to delete the keys matching %s in Etcd, %v", key, e)
 	}
 }
 
@@ -321,17 +411,20 @@ func (provider *Etcd) Init() error {
 
 // Reset method will reset or close provider.
 func (provider *Etcd) Reset() error {
-	return provider.Client.Close()
+	provider.supervisor.Close()
+	provider.stopNearCache()
+	provider.stopMonitor()
+
+	return provider.Client().Close()
 }
 
+// Reconnect replaces the client in the background, the concurrent calls share
+// the same reconnection.
 func (provider *Etcd) Reconnect() {
-	provider.reconnecting = true
+	provider.supervisor.Reconnect()
+}
 
-	if c, err := clientv3.New(provider.configuration); err == nil && c != nil {
-		provider.Client = c
-		provider.reconnecting = false
-	} else {
-		time.Sleep(10 * time.Second)
-		provider.Reconnect()
-	}
+// OnStateChange registers a callback called on each reconnection state transition.
+func (provider *Etcd) OnStateChange(listener func(core.ConnectionState)) {
+	provider.supervisor.OnStateChange(listener)
 }
@@ -1,12 +1,18 @@
 package etcd_test
 
 import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
 	"testing"
 	"time"
 
 	"github.com/darkweak/storages/core"
 	"github.com/darkweak/storages/etcd"
+	clientv3 "go.etcd.io/etcd/client/v3"
 	"go.uber.org/zap"
+	"google.golang.org/grpc/connectivity"
 )
 
 const (
@@ -119,3 +125,228 @@ func TestEtcd_Init(t *testing.T) {
 		t.Error("Impossible to init Etcd provider")
 	}
 }
+
+func TestEtcd_NearCache(t *testing.T) {
+	key := "MyNearCachedKey"
+	configuration := map[string]interface{}{
+		"Endpoints": []string{"http://etcd:2379"},
+		"NearCache": true,
+	}
+
+	// The watch would cover the whole keyspace without namespace.
+	if instance, err := etcd.Factory(core.CacheProvider{Configuration: configuration}, zap.NewNop().Sugar(), 0); err == nil || instance != nil {
+		t.Error("The near cache should require a namespace")
+	}
+
+	configuration["Namespace"] = "near/"
+
+	writer, _ := getNamespacedEtcdInstance("near/")
+	reader, _ := etcd.Factory(core.CacheProvider{Configuration: configuration}, zap.NewNop().Sugar(), 0)
+
+	defer func() {
+		_ = reader.Reset()
+	}()
+
+	// Let the watch be established.
+	time.Sleep(1 * time.Second)
+
+	_ = writer.Set(key, []byte(baseValue), 20*time.Second)
+
+	if string(reader.Get(key)) != baseValue {
+		t.Errorf("Key %s should exist", key)
+	}
+
+	_ = writer.Set(key, []byte("A"), 20*time.Second)
+	time.Sleep(1 * time.Second)
+
+	if string(reader.Get(key)) != "A" {
+		t.Errorf("The near cached key %s should be updated by the other node", key)
+	}
+
+	writer.Delete(key)
+	time.Sleep(1 * time.Second)
+
+	if 0 < len(reader.Get(key)) {
+		t.Errorf("The near cached key %s should be invalidated by the other node", key)
+	}
+}
+
+func TestEtcd_LeasePooling(t *testing.T) {
+	client, _ := getEtcdInstance()
+	provider := client.(*etcd.Etcd)
+
+	_ = client.Set("MyPooledKey1", []byte(baseValue), 20*time.Second)
+	_ = client.Set("MyPooledKey2", []byte(baseValue), 20*time.Second)
+
+	first, _ := provider.Client().Get(context.Background(), "MyPooledKey1")
+	second, _ := provider.Client().Get(context.Background(), "MyPooledKey2")
+
+	if len(first.Kvs) == 0 || len(second.Kvs) == 0 {
+		t.Fatal("The pooled keys should exist")
+	}
+
+	if first.Kvs[0].Lease != second.Kvs[0].Lease {
+		t.Errorf("The keys expiring in the same second should share a lease, got %d and %d", first.Kvs[0].Lease, second.Kvs[0].Lease)
+	}
+
+	ttl, err := provider.Client().TimeToLive(context.Background(), clientv3.LeaseID(first.Kvs[0].Lease))
+	if err != nil || ttl.TTL < 19 {
+		t.Errorf("The pooled lease shouldn't expire before the keys deadline, got %d: %v", ttl.TTL, err)
+	}
+}
+
+func getNamespacedEtcdInstance(namespace string) (core.Storer, error) {
+	return etcd.Factory(core.CacheProvider{
+		Configuration: map[string]interface{}{
+			"Endpoints":    []string{"http://etcd:2379"},
+			"Namespace":    namespace,
+			"ScanPageSize": 2,
+		},
+	}, zap.NewNop().Sugar(), 0)
+}
+
+func TestEtcd_NamespacedScansAndDeletes(t *testing.T) {
+	first, _ := getNamespacedEtcdInstance("first/")
+	second, _ := getNamespacedEtcdInstance("second/")
+
+	if health := first.(*etcd.Etcd).Health(); !health.Ready {
+		t.Errorf("The provider should be ready, got %#v", health)
+	}
+
+	keys := []string{"GET-http-a.com-/1", "GET-http-a.com-/2", "GET-http-a.com-/3", "GET-http-b.com-/1", "GET-http-b.com-/22"}
+	for _, key := range keys {
+		_ = first.Set(key, []byte(baseValue), 20*time.Second)
+		_ = second.Set(key, []byte(baseValue), 20*time.Second)
+	}
+
+	if mapped := first.MapKeys("GET-http-"); len(mapped) != len(keys) {
+		t.Errorf("The scan should return the %d keys of the namespace over the pages, got %v", len(keys), mapped)
+	}
+
+	// Dropped at once by prefix.
+	first.DeleteMany("^GET-http-a\\.com-")
+
+	// Scanned under the prefix only.
+	first.DeleteMany("^GET-http-b\\.com-/[0-9]$")
+
+	for key, exists := range map[string]bool{
+		"GET-http-a.com-/1":  false,
+		"GET-http-a.com-/3":  false,
+		"GET-http-b.com-/1":  false,
+		"GET-http-b.com-/22": true,
+	} {
+		if (len(first.Get(key)) != 0) != exists {
+			t.Errorf("The key %s existence should be %t", key, exists)
+		}
+	}
+
+	if mapped := second.MapKeys("GET-http-"); len(mapped) != len(keys) {
+		t.Errorf("The other namespace shouldn't be affected, got %v", mapped)
+	}
+
+	second.DeleteMany("^GET-http-")
+
+	if mapped := second.MapKeys(""); len(mapped) != 0 {
+		t.Errorf("The namespace should be empty, got %v", mapped)
+	}
+
+	if err := first.(core.PrefixDeleter).DeletePrefix(""); !errors.Is(err, core.ErrEmptyPrefix) {
+		t.Errorf("The empty prefix should be rejected, got %v", err)
+	}
+}
+
+func TestEtcd_BoundedStartup(t *testing.T) {
+	configuration := map[string]interface{}{
+		"Endpoints":      []string{"http://127.0.0.1:1"},
+		"StartupTimeout": "500ms",
+	}
+
+	start := time.Now()
+
+	instance, err := etcd.Factory(core.CacheProvider{Configuration: configuration}, zap.NewNop().Sugar(), 0)
+	if err == nil || instance != nil {
+		t.Error("The factory should fail when etcd is unreachable")
+	}
+
+	if time.Since(start) > 3*time.Second {
+		t.Errorf("The startup should be bounded by the StartupTimeout, took %s", time.Since(start))
+	}
+
+	configuration["StartDegraded"] = true
+
+	instance, err = etcd.Factory(core.CacheProvider{Configuration: configuration}, zap.NewNop().Sugar(), 0)
+	if err != nil || instance == nil {
+		t.Fatalf("The factory should start degraded, got %v", err)
+	}
+
+	defer func() {
+		_ = instance.Reset()
+	}()
+
+	if health := instance.(*etcd.Etcd).Health(); health.Ready {
+		t.Errorf("The degraded provider shouldn't be ready, got %#v", health)
+	}
+
+	// The degraded provider serves misses instead of waiting for etcd.
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		_ = instance.Get("Degraded")
+		_, _ = instance.GetMultiLevel("Degraded", req, &core.Revalidator{})
+		_ = instance.MapKeys("")
+		_ = instance.ListKeys()
+		instance.Delete("Degraded")
+		instance.DeleteMany("^Degraded")
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(3 * time.Second):
+		t.Error("The degraded provider calls should not wait for etcd")
+	}
+}
+
+func TestEtcd_Reconnect(t *testing.T) {
+	instance, _ := getEtcdInstance()
+	provider := instance.(*etcd.Etcd)
+
+	defer func() {
+		_ = provider.Reset()
+	}()
+
+	connected := make(chan struct{}, 1)
+	provider.OnStateChange(func(state core.ConnectionState) {
+		if state == core.Connected {
+			connected <- struct{}{}
+		}
+	})
+
+	previous := provider.Client()
+
+	// The concurrent calls share the same reconnection.
+	for range 5 {
+		go provider.Reconnect()
+	}
+
+	select {
+	case <-connected:
+	case <-time.After(5 * time.Second):
+		t.Fatal("The provider should reconnect")
+	}
+
+	if provider.Client() == previous {
+		t.Error("The client should be replaced")
+	}
+
+	if previous.ActiveConnection().GetState() != connectivity.Shutdown {
+		t.Error("The replaced client should be closed")
+	}
+
+	_ = provider.Set("Reconnected", []byte(baseValue), 20*time.Second)
+	if string(provider.Get("Reconnected")) != baseValue {
+		t.Error("The reconnected client should be used")
+	}
+}
@@ -0,0 +1,187 @@
+package etcd
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	clientv3 "go.etcd.io/etcd/client/v3"
+	"google.golang.org/grpc/connectivity"
+)
+
+const defaultStartupTimeout = 5 * time.Second
+
+// Health describes the connection to the etcd cluster.
+type Health struct {
+	// gRPC connectivity state (IDLE, CONNECTING, READY, TRANSIENT_FAILURE, SHUTDOWN).
+	State string
+	Ready bool
+	// Time of the last state transition.
+	Since time.Time
+}
+
+type startupConfiguration struct {
+	timeout  time.Duration
+	degraded bool
+}
+
+type health struct {
+	mu        sync.RWMutex
+	current   Health
+	listeners []func(Health)
+	// Stops the monitoring of the current client connection.
+	cancel context.CancelFunc
+}
+
+func parseStartupConfiguration(configuration interface{}) startupConfiguration {
+	cfg := startupConfiguration{timeout: defaultStartupTimeout}
+
+	configMap, ok := configuration.(map[string]interface{})
+	if !ok {
+		return cfg
+	}
+
+	if v := configMap["StartupTimeout"]; v != nil {
+		switch val := v.(type) {
+		case time.Duration:
+			cfg.timeout = val
+		case float64:
+			cfg.timeout = time.Duration(val)
+		case string:
+			if d, err := time.ParseDuration(val); err == nil {
+				cfg.timeout = d
+			}
+		}
+	}
+
+	if v := configMap["StartDegraded"]; v != nil {
+		if b, ok := v.(bool); ok {
+			cfg.degraded = b
+		} else if s, ok := v.(string); ok {
+			cfg.degraded, _ = strconv.ParseBool(s)
+		}
+	}
+
+	if cfg.timeout <= 0 {
+		cfg.timeout = defaultStartupTimeout
+	}
+
+	return cfg
+}
+
+// waitReady blocks until the client connection is ready or the context is done.
+func waitReady(ctx context.Context, cli *clientv3.Client) error {
+	conn := cli.ActiveConnection()
+	conn.Connect()
+
+	for {
+		state := conn.GetState()
+		if state == connectivity.Ready {
+			return nil
+		}
+
+		if !conn.WaitForStateChange(ctx, state) {
+			return fmt.Errorf("the etcd connection is not ready: %v", state)
+		}
+	}
+}
+
+// available returns an error while the connection is failing. clientv3 waits
+// for the connection to be ready by default, the calls of a degraded provider
+// would wait for etcd instead of missing.
+func (provider *Etcd) available() error {
+	state := provider.Client().ActiveConnection().GetState()
+	if state == connectivity.TransientFailure || state == connectivity.Shutdown {
+		return fmt.Errorf("the connection is not ready: %v", state)
+	}
+
+	return nil
+}
+
+// callContext bounds a call by the dial timeout, the connection may be lost
+// after the availability check.
+func (provider *Etcd) callContext() (context.Context, context.CancelFunc) {
+	if provider.timeout <= 0 {
+		return context.WithCancel(provider.ctx)
+	}
+
+	return context.WithTimeout(provider.ctx, provider.timeout)
+}
+
+// monitor follows the state transitions of the client connection in the
+// background, the previously monitored client is released.
+func (provider *Etcd) monitor(cli *clientv3.Client) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	provider.health.mu.Lock()
+	if provider.health.cancel != nil {
+		provider.health.cancel()
+	}
+
+	provider.health.cancel = cancel
+	provider.health.mu.Unlock()
+
+	conn := cli.ActiveConnection()
+	state := conn.GetState()
+	provider.setHealth(state)
+
+	go func() {
+		for conn.WaitForStateChange(ctx, state) {
+			state = conn.GetState()
+			provider.setHealth(state)
+		}
+	}()
+}
+
+func (provider *Etcd) stopMonitor() {
+	provider.health.mu.Lock()
+	defer provider.health.mu.Unlock()
+
+	if provider.health.cancel != nil {
+		provider.health.cancel()
+		provider.health.cancel = nil
+	}
+}
+
+func (provider *Etcd) setHealth(state connectivity.State) {
+	provider.health.mu.Lock()
+
+	if provider.health.current.State == state.String() {
+		provider.health.mu.Unlock()
+
+		return
+	}
+
+	current := Health{
+		State: state.String(),
+		Ready: state == connectivity.Ready,
+		Since: time.Now(),
+	}
+	provider.health.current = current
+	listeners := provider.health.listeners
+	provider.health.mu.Unlock()
+
+	provider.logger.Debugf("The etcd connection is now %s", current.State)
+
+	for _, listener := range listeners {
+		listener(current)
+	}
+}
+
+// Health returns the current state of the etcd connection.
+func (provider *Etcd) Health() Health {
+	provider.health.mu.RLock()
+	defer provider.health.mu.RUnlock()
+
+	return provider.health.current
+}
+
+// OnHealthChange registers a callback called on each connection state transition.
+func (provider *Etcd) OnHealthChange(listener func(Health)) {
+	provider.health.mu.Lock()
+	defer provider.health.mu.Unlock()
+
+	provider.health.listeners = append(provider.health.listeners, listener)
+}
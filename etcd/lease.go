@@ -0,0 +1,174 @@
+package etcd
+
+import (
+	"errors"
+	"math"
+	"sync"
+	"time"
+
+	"go.etcd.io/etcd/api/v3/v3rpc/rpctypes"
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+const defaultLeaseGranularity = time.Second
+
+type leaseEntry struct {
+	id  clientv3.LeaseID
+	err error
+	// Closed once the lease is granted or failed.
+	ready chan struct{}
+}
+
+// leasePool shares one lease between every key expiring in the same bucket,
+// instead of granting a new lease on each write. The buckets are rounded up
+// so a key never expires before its deadline.
+type leasePool struct {
+	mu          sync.Mutex
+	granularity time.Duration
+	// Leases keyed by their bucket deadline in unix nanoseconds.
+	leases    map[int64]*leaseEntry
+	lastSweep time.Time
+}
+
+func parseLeasePoolConfiguration(configuration interface{}) *leasePool {
+	pool := &leasePool{
+		granularity: defaultLeaseGranularity,
+		leases:      make(map[int64]*leaseEntry),
+	}
+
+	configMap, ok := configuration.(map[string]interface{})
+	if !ok {
+		return pool
+	}
+
+	if v := configMap["LeaseGranularity"]; v != nil {
+		switch val := v.(type) {
+		case time.Duration:
+			pool.granularity = val
+		case float64:
+			pool.granularity = time.Duration(val)
+		case string:
+			if d, err := time.ParseDuration(val); err == nil {
+				pool.granularity = d
+			}
+		}
+	}
+
+	if pool.granularity <= 0 {
+		pool.granularity = defaultLeaseGranularity
+	}
+
+	return pool
+}
+
+// deadline returns the end of the bucket the key expiring after duration belongs to.
+func (pool *leasePool) deadline(now time.Time, duration time.Duration) time.Time {
+	deadline := now.Add(duration)
+
+	bucket := deadline.Truncate(pool.granularity)
+	if bucket.Before(deadline) {
+		bucket = bucket.Add(pool.granularity)
+	}
+
+	return bucket
+}
+
+// sweep forgets the leases whose deadline passed, etcd already revoked them.
+// It must be called with the pool lock held.
+func (pool *leasePool) sweep(now time.Time) {
+	if now.Sub(pool.lastSweep) < pool.granularity {
+		return
+	}
+
+	pool.lastSweep = now
+
+	for deadline := range pool.leases {
+		if deadline <= now.UnixNano() {
+			delete(pool.leases, deadline)
+		}
+	}
+}
+
+// forget drops the lease so the next write on its bucket grants a new one.
+func (pool *leasePool) forget(id clientv3.LeaseID) {
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+
+	for deadline, entry := range pool.leases {
+		select {
+		case <-entry.ready:
+			if entry.id == id {
+				delete(pool.leases, deadline)
+			}
+		default:
+		}
+	}
+}
+
+// lease returns the lease shared by the keys expiring after duration, it's
+// granted by the first write on the bucket while the others wait for it.
+func (provider *Etcd) lease(duration time.Duration) (clientv3.LeaseID, error) {
+	pool := provider.leases
+	now := time.Now()
+	deadline := pool.deadline(now, duration)
+	key := deadline.UnixNano()
+
+	pool.mu.Lock()
+	pool.sweep(now)
+
+	entry, found := pool.leases[key]
+	if !found {
+		entry = &leaseEntry{ready: make(chan struct{})}
+		pool.leases[key] = entry
+	}
+	pool.mu.Unlock()
+
+	if found {
+		<-entry.ready
+
+		return entry.id, entry.err
+	}
+
+	ctx, cancel := provider.callContext()
+	defer cancel()
+
+	rs, err := provider.Client().Grant(ctx, int64(math.Ceil(deadline.Sub(now).Seconds())))
+	if err != nil {
+		entry.err = err
+
+		pool.mu.Lock()
+		if pool.leases[key] == entry {
+			delete(pool.leases, key)
+		}
+		pool.mu.Unlock()
+	} else {
+		entry.id = rs.ID
+	}
+
+	close(entry.ready)
+
+	return entry.id, entry.err
+}
+
+// put stores the value with a pooled lease expiring after duration. The lease
+// may be gone before its deadline (e.g. etcd restored), a new one is granted once then.
+func (provider *Etcd) put(key, value string, duration time.Duration) error {
+	ctx, cancel := provider.callContext()
+	defer cancel()
+
+	id, err := provider.lease(duration)
+	if err == nil {
+		_, err = provider.Client().Put(ctx, key, value, clientv3.WithLease(id))
+		if errors.Is(err, rpctypes.ErrLeaseNotFound) {
+			provider.leases.forget(id)
+
+			if id, err = provider.lease(duration); err == nil {
+				_, err = provider.Client().Put(ctx, key, value, clientv3.WithLease(id))
+			}
+		}
+	}
+
+	provider.nearCache.invalidate(key)
+
+	return err
+}
@@ -0,0 +1,282 @@
+package etcd
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+const (
+	defaultNearCacheSize = 10000
+	nearCacheRetryDelay  = time.Second
+)
+
+// The watch keeping the near cache coherent covers the namespace, it would
+// follow the whole shared keyspace without it.
+var errNearCacheWithoutNamespace = errors.New("the etcd near cache requires a Namespace")
+
+type nearEntry struct {
+	value []byte
+	// Revision of the read that filled the entry.
+	revision int64
+}
+
+// nearCache keeps the etcd values in memory. It's kept coherent by a watch on
+// the provider keys, every put or delete made by any node drops the local copy.
+type nearCache struct {
+	mu      sync.RWMutex
+	entries map[string]nearEntry
+	size    int
+	// Last revision applied from the watch, the older reads can't be cached.
+	revision int64
+	// Nothing is served nor cached until the watch is established.
+	ready bool
+
+	stop        context.CancelFunc
+	done        chan struct{}
+	watchMu     sync.Mutex
+	watchCancel context.CancelFunc
+}
+
+// parseNearCacheConfiguration returns nil when the near cache is not enabled
+// with NearCache or a positive NearCacheSize.
+func parseNearCacheConfiguration(configuration interface{}) *nearCache {
+	configMap, ok := configuration.(map[string]interface{})
+	if !ok {
+		return nil
+	}
+
+	enabled := false
+	size := defaultNearCacheSize
+
+	if v := configMap["NearCache"]; v != nil {
+		if b, ok := v.(bool); ok {
+			enabled = b
+		} else if s, ok := v.(string); ok {
+			enabled, _ = strconv.ParseBool(s)
+		}
+	}
+
+	if v := configMap["NearCacheSize"]; v != nil {
+		switch val := v.(type) {
+		case int:
+			size = val
+		case float64:
+			size = int(val)
+		case string:
+			size, _ = strconv.Atoi(val)
+		}
+
+		enabled = enabled || size > 0
+	}
+
+	if !enabled || size <= 0 {
+		return nil
+	}
+
+	return &nearCache{
+		entries: make(map[string]nearEntry),
+		size:    size,
+	}
+}
+
+func (nc *nearCache) get(key string) ([]byte, bool) {
+	if nc == nil {
+		return nil, false
+	}
+
+	nc.mu.RLock()
+	defer nc.mu.RUnlock()
+
+	if !nc.ready {
+		return nil, false
+	}
+
+	entry, found := nc.entries[key]
+
+	return entry.value, found
+}
+
+// fill stores the value read at the given revision, unless the watch already
+// applied a newer revision that may have changed it.
+func (nc *nearCache) fill(key string, value []byte, revision int64) {
+	if nc == nil {
+		return
+	}
+
+	nc.mu.Lock()
+	defer nc.mu.Unlock()
+
+	if !nc.ready || revision < nc.revision {
+		return
+	}
+
+	if _, found := nc.entries[key]; !found && len(nc.entries) >= nc.size {
+		// Drop any entry to make room, the map iteration order is random.
+		for k := range nc.entries {
+			delete(nc.entries, k)
+
+			break
+		}
+	}
+
+	nc.entries[key] = nearEntry{value: value, revision: revision}
+}
+
+func (nc *nearCache) invalidate(key string) {
+	if nc == nil {
+		return
+	}
+
+	nc.mu.Lock()
+	defer nc.mu.Unlock()
+
+	delete(nc.entries, key)
+}
+
+func (nc *nearCache) invalidatePrefix(prefix string) {
+	if nc == nil {
+		return
+	}
+
+	nc.mu.Lock()
+	defer nc.mu.Unlock()
+
+	for key := range nc.entries {
+		if strings.HasPrefix(key, prefix) {
+			delete(nc.entries, key)
+		}
+	}
+}
+
+// apply drops the local copies changed by the watched events.
+func (nc *nearCache) apply(response clientv3.WatchResponse) {
+	nc.mu.Lock()
+	defer nc.mu.Unlock()
+
+	for _, event := range response.Events {
+		key := string(event.Kv.Key)
+		if entry, found := nc.entries[key]; found && entry.revision < event.Kv.ModRevision {
+			delete(nc.entries, key)
+		}
+	}
+
+	nc.revision = max(nc.revision, response.Header.Revision)
+}
+
+// start clears the entries and serves them again from the watch revision.
+func (nc *nearCache) start(revision int64) {
+	nc.mu.Lock()
+	defer nc.mu.Unlock()
+
+	nc.entries = make(map[string]nearEntry)
+	nc.revision = revision
+	nc.ready = true
+}
+
+// clear drops every entry and stops serving them until the watch is established again.
+func (nc *nearCache) clear() {
+	nc.mu.Lock()
+	defer nc.mu.Unlock()
+
+	nc.entries = make(map[string]nearEntry)
+	nc.ready = false
+}
+
+// resync cancels the current watch, the watch loop clears the entries and starts a new one.
+func (nc *nearCache) resync() {
+	if nc == nil {
+		return
+	}
+
+	nc.clear()
+
+	nc.watchMu.Lock()
+	defer nc.watchMu.Unlock()
+
+	if nc.watchCancel != nil {
+		nc.watchCancel()
+	}
+}
+
+// startNearCache runs the watch keeping the near cache coherent, it's a no-op when disabled.
+func (provider *Etcd) startNearCache() {
+	nc := provider.nearCache
+	if nc == nil {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	nc.stop = cancel
+	nc.done = make(chan struct{})
+
+	go provider.nearCacheLoop(ctx)
+}
+
+// stopNearCache stops the watch and waits for the loop to exit.
+func (provider *Etcd) stopNearCache() {
+	nc := provider.nearCache
+	if nc == nil || nc.stop == nil {
+		return
+	}
+
+	nc.stop()
+	<-nc.done
+	nc.clear()
+}
+
+func (provider *Etcd) nearCacheLoop(ctx context.Context) {
+	defer close(provider.nearCache.done)
+
+	for {
+		provider.nearCache.clear()
+		provider.watchNearCache(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(nearCacheRetryDelay):
+		}
+	}
+}
+
+// watchNearCache applies the events until the watch fails, is compacted or is canceled.
+func (provider *Etcd) watchNearCache(ctx context.Context) {
+	nc := provider.nearCache
+
+	watchCtx, cancel := context.WithCancel(clientv3.WithRequireLeader(ctx))
+	defer cancel()
+
+	nc.watchMu.Lock()
+	nc.watchCancel = cancel
+	nc.watchMu.Unlock()
+
+	// Every provider key, the client namespace (required) restricts them to its prefix.
+	watcher := provider.Client().Watch(
+		watchCtx,
+		"",
+		clientv3.WithPrefix(),
+		clientv3.WithCreatedNotify(),
+	)
+
+	for response := range watcher {
+		if err := response.Err(); err != nil {
+			provider.logger.Warnf("The etcd near cache watch stopped, resync: %v", err)
+
+			return
+		}
+
+		if response.Created {
+			nc.start(response.Header.Revision)
+
+			continue
+		}
+
+		nc.apply(response)
+	}
+}
@@ -0,0 +1,168 @@
+package etcd
+
+import (
+	"strconv"
+
+	"github.com/darkweak/storages/core"
+	"go.etcd.io/etcd/api/v3/mvccpb"
+	clientv3 "go.etcd.io/etcd/client/v3"
+	"go.etcd.io/etcd/client/v3/namespace"
+)
+
+const (
+	defaultScanPageSize = 1000
+	// Maximum number of operations per transaction allowed by etcd by default.
+	deleteBatchSize = 128
+)
+
+var _ core.PrefixDeleter = (*Etcd)(nil)
+
+type scanConfiguration struct {
+	namespace string
+	pageSize  int64
+}
+
+func parseScanConfiguration(configuration interface{}) scanConfiguration {
+	cfg := scanConfiguration{pageSize: defaultScanPageSize}
+
+	configMap, ok := configuration.(map[string]interface{})
+	if !ok {
+		return cfg
+	}
+
+	if v, ok := configMap["Namespace"].(string); ok {
+		cfg.namespace = v
+	}
+
+	if v := configMap["ScanPageSize"]; v != nil {
+		switch val := v.(type) {
+		case int:
+			cfg.pageSize = int64(val)
+		case float64:
+			cfg.pageSize = int64(val)
+		case string:
+			cfg.pageSize, _ = strconv.ParseInt(val, 10, 64)
+		}
+	}
+
+	if cfg.pageSize <= 0 {
+		cfg.pageSize = defaultScanPageSize
+	}
+
+	return cfg
+}
+
+// newClient creates the etcd client, every key is prefixed by the namespace if any.
+func newClient(configuration clientv3.Config, prefix string) (*clientv3.Client, error) {
+	cli, err := clientv3.New(configuration)
+	if err != nil || prefix == "" {
+		return cli, err
+	}
+
+	cli.KV = namespace.NewKV(cli.KV, prefix)
+	cli.Watcher = namespace.NewWatcher(cli.Watcher, prefix)
+	cli.Lease = namespace.NewLease(cli.Lease, prefix)
+
+	return cli, nil
+}
+
+// scan calls fn for every key starting with the prefix. The keys are loaded
+// page by page from the same revision, so the whole keyspace is never loaded at once.
+func (provider *Etcd) scan(prefix string, fn func(kv *mvccpb.KeyValue), opts ...clientv3.OpOption) error {
+	start, end := prefix, clientv3.GetPrefixRangeEnd(prefix)
+	if prefix == "" {
+		start, end = "\x00", "\x00"
+	}
+
+	var revision int64
+
+	for {
+		options := append([]clientv3.OpOption{
+			clientv3.WithRange(end),
+			clientv3.WithLimit(provider.scanConfiguration.pageSize),
+			clientv3.WithRev(revision),
+		}, opts...)
+
+		ctx, cancel := provider.callContext()
+		result, err := provider.Client().Get(ctx, start, options...)
+
+		cancel()
+
+		if err != nil {
+			return err
+		}
+
+		revision = result.Header.Revision
+
+		for _, kv := range result.Kvs {
+			fn(kv)
+		}
+
+		if !result.More || len(result.Kvs) == 0 {
+			return nil
+		}
+
+		start = string(result.Kvs[len(result.Kvs)-1].Key) + "\x00"
+	}
+}
+
+// deleteMatching deletes the keys selected by the matcher, only the keys
+// starting with its literal prefix are scanned.
+func (provider *Etcd) deleteMatching(matcher core.Matcher) error {
+	prefix, exact := matcher.Prefix()
+	if exact && prefix != "" {
+		return provider.DeletePrefix(prefix)
+	}
+
+	keys := []string{}
+
+	err := provider.scan(prefix, func(kv *mvccpb.KeyValue) {
+		if matcher.Match(string(kv.Key)) {
+			keys = append(keys, string(kv.Key))
+		}
+	}, clientv3.WithKeysOnly())
+	if err != nil {
+		return err
+	}
+
+	for len(keys) > 0 {
+		batch := keys[:min(len(keys), deleteBatchSize)]
+		keys = keys[len(batch):]
+
+		ops := make([]clientv3.Op, 0, len(batch))
+		for _, key := range batch {
+			ops = append(ops, clientv3.OpDelete(key))
+		}
+
+		ctx, cancel := provider.callContext()
+		_, err = provider.Client().Txn(ctx).Then(ops...).Commit()
+
+		cancel()
+
+		if err != nil {
+			return err
+		}
+
+		for _, key := range batch {
+			provider.nearCache.invalidate(key)
+		}
+	}
+
+	return nil
+}
+
+// DeletePrefix method will delete every key starting with the prefix in a single range deletion.
+func (provider *Etcd) DeletePrefix(prefix string) error {
+	// Without Namespace the empty prefix would be the whole etcd keyspace.
+	if prefix == "" {
+		return core.ErrEmptyPrefix
+	}
+
+	ctx, cancel := provider.callContext()
+	defer cancel()
+
+	_, err := provider.Client().Delete(ctx, prefix, clientv3.WithPrefix())
+	provider.nearCache.invalidatePrefix(prefix)
+
+	return err
+}
@@ -8,7 +8,6 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
-	"regexp"
 	"strings"
 	"time"
 
@@ -17,15 +16,15 @@ import (
 	"github.com/redis/go-redis/v9"
 )
 
+var mappingMergeScript = redis.NewScript(core.MappingMergeScript)
+
 // Redis provider type.
 type Redis struct {
-	inClient      redis.UniversalClient
+	supervisor    *core.Supervisor[redis.UniversalClient]
 	stale         time.Duration
 	ctx           context.Context
 	logger        core.Logger
 	configuration redis.UniversalOptions
-	close         func() error
-	reconnecting  bool
 	hashtags      string
 }
 
@@ -81,19 +80,33 @@ func Factory(redisConfiguration core.CacheProvider, logger core.Logger, stale ti
 		options.ClientName = "souin-redis"
 	}
 
-	cli := redis.NewUniversalClient(&options)
+	dial := func() (redis.UniversalClient, error) {
+		cli := newClient(&options)
+		if err := cli.Ping(context.Background()).Err(); err != nil {
+			_ = cli.Close()
+
+			return nil, err
+		}
+
+		return cli, nil
+	}
 
 	return &Redis{
-		inClient:      cli,
+		supervisor: core.NewSupervisor(newClient(&options), dial, func(cli redis.UniversalClient) {
+			_ = cli.Close()
+		}, logger),
 		ctx:           context.Background(),
 		stale:         stale,
 		configuration: options,
 		logger:        logger,
-		close:         cli.Close,
 		hashtags:      hashtags,
 	}, nil
 }
 
+func (provider *Redis) client() redis.UniversalClient {
+	return provider.supervisor.Client()
+}
+
 // Name returns the storer name.
 func (provider *Redis) Name() string {
 	return "REDIS"
@@ -101,7 +114,7 @@ func (provider *Redis) Name() string {
 
 // Uuid returns an unique identifier.
 func (provider *Redis) Uuid() string {
-	return fmt.Sprintf(
+	uuid := fmt.Sprintf(
 		"%s-%s-%d-%s-%s",
 		strings.Join(provider.configuration.Addrs, ","),
 		provider.configuration.Username,
@@ -109,23 +122,44 @@ func (provider *Redis) Uuid() string {
 		provider.configuration.ClientName,
 		provider.stale,
 	)
+
+	// The same Sentinels may monitor several masters.
+	if provider.configuration.MasterName != "" {
+		uuid += "-" + provider.configuration.MasterName
+	}
+
+	return uuid
 }
 
 // ListKeys method returns the list of existing keys.
 func (provider *Redis) ListKeys() []string {
-	if provider.reconnecting {
+	if provider.supervisor.Reconnecting() {
 		provider.logger.Error("Impossible to list the redis keys while reconnecting.")
 
 		return []string{}
 	}
 
 	keys := []string{}
+	mappingKeys := []string{}
+
+	err := provider.scan(provider.hashtags+core.MappingKeyPrefix+"*", func(key string) {
+		mappingKeys = append(mappingKeys, key)
+	})
+	if err != nil {
+		provider.Reconnect()
 
-	iter := provider.inClient.Scan(provider.ctx, 0, provider.hashtags+core.MappingKeyPrefix+"*", 0).Iterator()
-	for iter.Next(provider.ctx) {
-		value := provider.Get(iter.Val())
+		provider.logger.Error(err)
 
-		mapping, err := core.DecodeMapping(value)
+		return []string{}
+	}
+
+	mappings, err := provider.mget(mappingKeys)
+	if err != nil {
+		provider.logger.Error(err)
+	}
+
+	for _, value := range mappings {
+		mapping, err := core.DecodeMapping([]byte(value))
 		if err != nil {
 			continue
 		}
@@ -139,16 +173,6 @@ func (provider *Redis) ListKeys() []string {
 		}
 	}
 
-	if err := iter.Err(); err != nil {
-		if !provider.reconnecting {
-			go provider.Reconnect()
-		}
-
-		provider.logger.Error(err)
-
-		return []string{}
-	}
-
 	return keys
 }
 
@@ -157,26 +181,20 @@ func (provider *Redis) MapKeys(prefix string) map[string]string {
 	mapKeys := map[string]string{}
 	keys := []string{}
 
-	iter := provider.inClient.Scan(provider.ctx, 0, prefix+"*", 0).Iterator()
-	for iter.Next(provider.ctx) {
-		keys = append(keys, iter.Val())
-	}
-
-	if err := iter.Err(); err != nil {
+	if err := provider.scan(prefix+"*", func(key string) {
+		keys = append(keys, key)
+	}); err != nil {
 		return mapKeys
 	}
 
-	vals, err := provider.inClient.MGet(provider.ctx, keys...).Result()
+	vals, err := provider.mget(keys)
 	if err != nil {
 		return mapKeys
 	}
 
-	for idx, item := range keys {
+	for item, value := range vals {
 		k, _ := strings.CutPrefix(item, prefix)
-
-		if vals[idx] != nil {
-			mapKeys[k] = vals[idx].(string)
-		}
+		mapKeys[k] = value
 	}
 
 	return mapKeys
@@ -184,7 +202,7 @@ func (provider *Redis) MapKeys(prefix string) map[string]string {
 
 // GetMultiLevel tries to load the key and check if one of linked keys is a fresh/stale candidate.
 func (provider *Redis) GetMultiLevel(key string, req *http.Request, validator *core.Revalidator) (fresh *http.Response, stale *http.Response) {
-	b, e := provider.inClient.Get(provider.ctx, provider.hashtags+core.MappingKeyPrefix+key).Bytes()
+	b, e := provider.client().Get(provider.ctx, provider.hashtags+core.MappingKeyPrefix+key).Bytes()
 	if e != nil {
 		return fresh, stale
 	}
@@ -205,25 +223,44 @@ func (provider *Redis) SetMultiLevel(baseKey, variedKey string, value []byte, va
 		return err
 	}
 
-	if err := provider.Set(provider.hashtags+variedKey, compressed.Bytes(), duration); err != nil {
-		provider.logger.Errorf("Impossible to set value into Redis, %v", err)
+	if provider.supervisor.Reconnecting() {
+		provider.logger.Error("Impossible to set the redis value while reconnecting.")
+
+		return errors.New("reconnecting error")
+	}
+
+	bodyKey := provider.hashtags + variedKey
 
+	entry, err := core.MappingUpdater(bodyKey, nil, provider.logger, now, now.Add(duration), now.Add(duration+provider.stale), variedHeaders, etag, realKey)
+	if err != nil {
 		return err
 	}
 
 	mappingKey := provider.hashtags + core.MappingKeyPrefix + baseKey
-	result, err := provider.inClient.Get(provider.ctx, mappingKey).Bytes()
+	args := []interface{}{
+		entry,
+		bodyKey,
+		now.UnixMilli(),
+		compressed.Bytes(),
+		(duration + provider.stale).Milliseconds(),
+	}
 
-	if err != nil && !errors.Is(err, redis.Nil) {
-		return err
+	client := provider.client()
+
+	// A cluster rejects the multi-key commands crossing slots, without
+	// HashTag only the mapping merge stays atomic.
+	if _, cluster := client.(*redis.ClusterClient); !cluster || core.ClusterSlot(mappingKey) == core.ClusterSlot(bodyKey) {
+		err = mappingMergeScript.Run(provider.ctx, client, []string{mappingKey, bodyKey}, args...).Err()
+	} else {
+		err = client.Set(provider.ctx, bodyKey, compressed.Bytes(), duration+provider.stale).Err()
+		if err == nil {
+			err = mappingMergeScript.Run(provider.ctx, client, []string{mappingKey}, args...).Err()
+		}
 	}
 
-	val, err := core.MappingUpdater(provider.hashtags+variedKey, result, provider.logger, now, now.Add(duration), now.Add(duration+provider.stale), variedHeaders, etag, realKey)
 	if err != nil {
-		return err
-	}
+		provider.Reconnect()
 
-	if err = provider.Set(mappingKey, val, -1); err != nil {
 		provider.logger.Errorf("Impossible to set value into Redis, %v", err)
 	}
 
@@ -232,16 +269,16 @@ func (provider *Redis) SetMultiLevel(baseKey, variedKey string, value []byte, va
 
 // Get method returns the populated response if exists, empty response then.
 func (provider *Redis) Get(key string) (item []byte) {
-	if provider.reconnecting {
+	if provider.supervisor.Reconnecting() {
 		provider.logger.Error("Impossible to get the redis key while reconnecting.")
 
 		return
 	}
 
-	result, err := provider.inClient.Get(provider.ctx, key).Result()
+	result, err := provider.client().Get(provider.ctx, key).Result()
 	if err != nil {
-		if !errors.Is(err, redis.Nil) && !provider.reconnecting {
-			go provider.Reconnect()
+		if !errors.Is(err, redis.Nil) {
+			provider.Reconnect()
 		}
 
 		return
@@ -254,13 +291,13 @@ func (provider *Redis) Get(key string) (item []byte) {
 
 // Prefix method returns the keys that match the prefix key.
 func (provider *Redis) Prefix(key string) []string {
-	// keys, _ := provider.inClient.Do(provider.ctx, provider.inClient.B().Keys().Pattern(key+"*").Build()).AsStrSlice()
+	// keys, _ := provider.client().Do(provider.ctx, provider.client().B().Keys().Pattern(key+"*").Build()).AsStrSlice()
 	return []string{}
 }
 
 // Set method will store the response in Etcd provider.
 func (provider *Redis) Set(key string, value []byte, duration time.Duration) error {
-	if provider.reconnecting {
+	if provider.supervisor.Reconnecting() {
 		provider.logger.Error("Impossible to set the redis value while reconnecting.")
 
 		return errors.New("reconnecting error")
@@ -272,11 +309,9 @@ func (provider *Redis) Set(key string, value []byte, duration time.Duration) err
 		duration += provider.stale
 	}
 
-	err := provider.inClient.Set(provider.ctx, key, value, duration).Err()
+	err := provider.client().Set(provider.ctx, key, value, duration).Err()
 	if err != nil {
-		if !provider.reconnecting {
-			go provider.Reconnect()
-		}
+		provider.Reconnect()
 
 		provider.logger.Errorf("Impossible to set value into Redis, %v", err)
 	}
@@ -286,44 +321,45 @@ func (provider *Redis) Set(key string, value []byte, duration time.Duration) err
 
 // Delete method will delete the response in Etcd provider if exists corresponding to key param.
 func (provider *Redis) Delete(key string) {
-	if provider.reconnecting {
+	if provider.supervisor.Reconnecting() {
 		provider.logger.Error("Impossible to delete the redis key while reconnecting.")
 
 		return
 	}
 
-	_ = provider.inClient.Del(provider.ctx, key)
+	_ = provider.client().Del(provider.ctx, key)
 }
 
 // DeleteMany method will delete the responses in Redis provider if exists corresponding to the regex key param.
 func (provider *Redis) DeleteMany(key string) {
-	if provider.reconnecting {
+	if provider.supervisor.Reconnecting() {
 		provider.logger.Error("Impossible to delete the redis keys while reconnecting.")
 
 		return
 	}
 
-	rgKey, err := regexp.Compile(key)
+	matcher, err := core.ParseMatcher(key)
 	if err != nil {
 		return
 	}
 
 	keys := []string{}
-	iter := provider.inClient.Scan(provider.ctx, 0, "*", 0).Iterator()
 
-	for iter.Next(provider.ctx) {
-		if rgKey.MatchString(iter.Val()) {
-			keys = append(keys, iter.Val())
+	// SCAN MATCH only narrows the keys down to the matcher literal prefix.
+	err = provider.scan(matcher.Glob(), func(key string) {
+		if matcher.Match(key) {
+			keys = append(keys, key)
 		}
-	}
-
-	if iter.Err() != nil && !provider.reconnecting {
-		go provider.Reconnect()
+	})
+	if err != nil {
+		provider.Reconnect()
 
 		return
 	}
 
-	provider.inClient.Del(provider.ctx, keys...)
+	if err = provider.del(keys); err != nil {
+		provider.logger.Errorf("Impossible to delete the keys matching %s in Redis, %v", key, err)
+	}
 }
 
 // Init method will.
@@ -333,22 +369,18 @@ func (provider *Redis) Init() error {
 
 // Reset method will reset or close provider.
 func (provider *Redis) Reset() error {
-	if provider.reconnecting {
-		provider.logger.Error("Impossible to reset the redis instance while reconnecting.")
-
-		return nil
-	}
+	provider.supervisor.Close()
 
-	return provider.inClient.Close()
+	return provider.client().Close()
 }
 
+// Reconnect replaces the client in the background, the concurrent calls share
+// the same reconnection.
 func (provider *Redis) Reconnect() {
-	provider.reconnecting = true
+	provider.supervisor.Reconnect()
+}
 
-	if provider.inClient = redis.NewUniversalClient(&provider.configuration); provider.inClient != nil {
-		provider.reconnecting = false
-	} else {
-		time.Sleep(10 * time.Second)
-		provider.Reconnect()
-	}
+// OnStateChange registers a callback called on each connection state transition.
+func (provider *Redis) OnStateChange(listener func(core.ConnectionState)) {
+	provider.supervisor.OnStateChange(listener)
 }
@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"github.com/darkweak/storages/core"
+	"github.com/darkweak/storages/core/storertest"
 	redis "github.com/darkweak/storages/go-redis"
 	"go.uber.org/zap"
 )
@@ -167,3 +168,87 @@ func TestRedis_DeleteMany(t *testing.T) {
 		t.Error("The map should be empty")
 	}
 }
+
+func TestRedis_SetMultiLevel(t *testing.T) {
+	client, _ := getRedisInstance()
+
+	storertest.SetMultiLevel(t, client)
+}
+
+func TestRedis_Cluster(t *testing.T) {
+	client, _ := redis.Factory(core.CacheProvider{Configuration: map[string]interface{}{
+		"Addrs": []string{"localhost:7000", "localhost:7001", "localhost:7002"},
+	}}, zap.NewNop().Sugar(), 0)
+
+	storertest.Cluster(t, client)
+}
+
+func TestRedis_DeleteManyMatchers(t *testing.T) {
+	client, _ := getRedisInstance()
+
+	defer client.DeleteMany(".*")
+
+	for _, key := range []string{"MATCHER_a.b", "MATCHER_axb", "MATCHER_[c]", "OTHER_a.b"} {
+		_ = client.Set(key, []byte(baseValue), 20*time.Second)
+	}
+
+	client.DeleteMany("prefix:MATCHER_[")
+
+	if len(client.Get("MATCHER_[c]")) != 0 || len(client.MapKeys("")) != 3 {
+		t.Error("The prefix matcher should only delete MATCHER_[c]")
+	}
+
+	client.DeleteMany("glob:MATCHER_a?b")
+
+	if len(client.MapKeys("")) != 1 {
+		t.Error("The glob matcher should delete MATCHER_a.b and MATCHER_axb")
+	}
+
+	client.DeleteMany("a\\.b$")
+
+	if len(client.MapKeys("")) != 0 {
+		t.Error("The regex matcher should delete OTHER_a.b")
+	}
+}
+
+func TestRedis_SentinelReplicaReads(t *testing.T) {
+	client, _ := redis.Factory(core.CacheProvider{Configuration: map[string]interface{}{
+		"Addrs":      []string{"localhost:26379"},
+		"MasterName": "mymaster",
+		"ReadOnly":   true,
+	}}, zap.NewNop().Sugar(), 0)
+
+	if err := client.Set("SentinelKey", []byte(baseValue), 20*time.Second); err != nil {
+		storertest.Unreachable(t, "redis sentinel", err)
+	}
+
+	defer client.Delete("SentinelKey")
+
+	if string(storertest.EventuallyGet(client, "SentinelKey")) != baseValue {
+		t.Error("The key SentinelKey should be readable through the sentinel")
+	}
+}
+
+func TestRedis_ClusterReplicaReads(t *testing.T) {
+	client, _ := redis.Factory(core.CacheProvider{Configuration: map[string]interface{}{
+		"Addrs":         []string{"localhost:7000", "localhost:7001", "localhost:7002"},
+		"ReadOnly":      true,
+		"RouteRandomly": true,
+	}}, zap.NewNop().Sugar(), 0)
+
+	if err := client.Set("ReplicaKey", []byte(baseValue), 20*time.Second); err != nil {
+		storertest.Unreachable(t, "redis cluster", err)
+	}
+
+	defer client.Delete("ReplicaKey")
+
+	if string(storertest.EventuallyGet(client, "ReplicaKey")) != baseValue {
+		t.Error("The key ReplicaKey should be readable from the replicas")
+	}
+}
+
+func TestRedis_MapKeysBatches(t *testing.T) {
+	client, _ := getRedisInstance()
+
+	storertest.MapKeysBatches(t, client)
+}
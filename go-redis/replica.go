@@ -0,0 +1,27 @@
+package redis
+
+import (
+	"github.com/redis/go-redis/v9"
+)
+
+// newClient creates the client matching the options. The read-only commands
+// are sent to the replicas when ReadOnly, RouteRandomly or RouteByLatency is set:
+//   - with several Addrs the cluster replicas serve the reads, ReadOnly picks any
+//     replica of the slot, RouteRandomly any node of the slot and RouteByLatency
+//     the closest one.
+//   - with a MasterName the Addrs are the Sentinels, the reads are routed over
+//     the master and its replicas, randomly unless RouteByLatency is set.
+//     The writes are still sent to the master elected by the Sentinels.
+func newClient(options *redis.UniversalOptions) redis.UniversalClient {
+	if options.MasterName == "" || !(options.ReadOnly || options.RouteRandomly || options.RouteByLatency) {
+		return redis.NewUniversalClient(options)
+	}
+
+	// The universal client ignores the routing options with Sentinel and
+	// ReplicaOnly would send the writes to a replica.
+	failover := options.Failover()
+	failover.RouteByLatency = options.RouteByLatency
+	failover.RouteRandomly = options.RouteRandomly || !options.RouteByLatency
+
+	return redis.NewFailoverClusterClient(failover)
+}
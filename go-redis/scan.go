@@ -0,0 +1,95 @@
+package redis
+
+import (
+	"context"
+	"sync"
+
+	"github.com/darkweak/storages/core"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// Number of keys asked per SCAN call.
+	scanCount = 1000
+	// Maximum number of keys sent in a single multi-key command.
+	batchSize = 1000
+	// Maximum number of multi-key commands sent in a single pipeline.
+	pipelineSize = 16
+)
+
+// scan calls fn for every key matching the glob pattern. A SCAN only walks the
+// node it is sent to, so every master is scanned when connected to a cluster.
+func (provider *Redis) scan(match string, fn func(key string)) error {
+	var mu sync.Mutex
+
+	scanNode := func(ctx context.Context, client redis.Cmdable) error {
+		iter := client.Scan(ctx, 0, match, scanCount).Iterator()
+		for iter.Next(ctx) {
+			mu.Lock()
+			fn(iter.Val())
+			mu.Unlock()
+		}
+
+		return iter.Err()
+	}
+
+	if cluster, ok := provider.client().(*redis.ClusterClient); ok {
+		return cluster.ForEachMaster(provider.ctx, func(ctx context.Context, client *redis.Client) error {
+			return scanNode(ctx, client)
+		})
+	}
+
+	return scanNode(provider.ctx, provider.client())
+}
+
+// mget returns the values of the existing keys. The keys are grouped by slot
+// and the MGET commands pipelined by chunks of pipelineSize, the cluster client
+// routes each one to its node.
+func (provider *Redis) mget(keys []string) (map[string]string, error) {
+	values := make(map[string]string, len(keys))
+	batches := core.GroupBySlot(keys, batchSize)
+
+	for len(batches) > 0 {
+		chunk := batches[:min(len(batches), pipelineSize)]
+		batches = batches[len(chunk):]
+		commands := make([]*redis.SliceCmd, 0, len(chunk))
+
+		_, err := provider.client().Pipelined(provider.ctx, func(pipe redis.Pipeliner) error {
+			for _, batch := range chunk {
+				commands = append(commands, pipe.MGet(provider.ctx, batch...))
+			}
+
+			return nil
+		})
+		if err != nil {
+			return values, err
+		}
+
+		for i, command := range commands {
+			for j, value := range command.Val() {
+				if v, ok := value.(string); ok {
+					values[chunk[i][j]] = v
+				}
+			}
+		}
+	}
+
+	return values, nil
+}
+
+// del deletes the keys with one DEL per slot-grouped batch, pipelined to their nodes.
+func (provider *Redis) del(keys []string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+
+	_, err := provider.client().Pipelined(provider.ctx, func(pipe redis.Pipeliner) error {
+		for _, batch := range core.GroupBySlot(keys, batchSize) {
+			pipe.Del(provider.ctx, batch...)
+		}
+
+		return nil
+	})
+
+	return err
+}
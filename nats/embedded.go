@@ -0,0 +1,214 @@
+package nats
+
+import (
+	"errors"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/darkweak/storages/core"
+	"github.com/nats-io/nats-server/v2/server"
+	nats "github.com/nats-io/nats.go"
+)
+
+const embeddedStartupTimeout = 10 * time.Second
+
+var errEmbeddedNotReady = errors.New("the embedded nats server isn't ready")
+
+// embeddedConfiguration is the in-process server configuration, set with
+// "mode": "local". Without listen address a standalone server only accepts
+// the in-process connection of the provider.
+type embeddedConfiguration struct {
+	serverName string
+	listen     string
+	// JetStream storage of the buckets, the embedded server keeps them in
+	// memory unless storage is "file".
+	storage  nats.StorageType
+	storeDir string
+	replicas int
+
+	clusterName   string
+	clusterListen string
+	routes        []string
+}
+
+func parseEmbeddedConfiguration(configuration interface{}) (embeddedConfiguration, bool) {
+	var cfg embeddedConfiguration
+
+	configMap, ok := configuration.(map[string]interface{})
+	if !ok {
+		return cfg, false
+	}
+
+	if mode, _ := configMap["mode"].(string); mode != "local" {
+		return cfg, false
+	}
+
+	cfg.storage = nats.MemoryStorage
+
+	cfg.serverName, _ = configMap["server_name"].(string)
+	cfg.listen, _ = configMap["listen"].(string)
+	cfg.storeDir, _ = configMap["store_dir"].(string)
+
+	if storage, _ := configMap["storage"].(string); storage == "file" {
+		cfg.storage = nats.FileStorage
+	}
+
+	switch v := configMap["replicas"].(type) {
+	case int:
+		cfg.replicas = v
+	case float64:
+		cfg.replicas = int(v)
+	case string:
+		cfg.replicas, _ = strconv.Atoi(v)
+	}
+
+	if cluster, ok := configMap["cluster"].(map[string]interface{}); ok {
+		cfg.clusterName, _ = cluster["name"].(string)
+		cfg.clusterListen, _ = cluster["listen"].(string)
+
+		switch routes := cluster["routes"].(type) {
+		case string:
+			cfg.routes = strings.Split(routes, ",")
+		case []string:
+			cfg.routes = routes
+		case []interface{}:
+			for _, route := range routes {
+				if r, ok := route.(string); ok {
+					cfg.routes = append(cfg.routes, r)
+				}
+			}
+		}
+	}
+
+	return cfg, true
+}
+
+func splitHostPort(address string) (string, int, error) {
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return "", 0, err
+	}
+
+	p, err := strconv.Atoi(port)
+
+	return host, p, err
+}
+
+func (cfg embeddedConfiguration) serverOptions() (*server.Options, error) {
+	opts := &server.Options{
+		ServerName: cfg.serverName,
+		JetStream:  true,
+		StoreDir:   cfg.storeDir,
+		DontListen: cfg.listen == "",
+		NoSigs:     true,
+	}
+
+	if cfg.listen != "" {
+		host, port, err := splitHostPort(cfg.listen)
+		if err != nil {
+			return nil, err
+		}
+
+		opts.Host, opts.Port = host, port
+	}
+
+	if cfg.clusterListen != "" {
+		host, port, err := splitHostPort(cfg.clusterListen)
+		if err != nil {
+			return nil, err
+		}
+
+		// The routes are only started once the clients are accepted.
+		if opts.DontListen {
+			opts.DontListen = false
+			opts.Host, opts.Port = "127.0.0.1", server.RANDOM_PORT
+		}
+
+		opts.Cluster.Name = cfg.clusterName
+		opts.Cluster.Host, opts.Cluster.Port = host, port
+		opts.Routes = server.RoutesFromStr(strings.Join(cfg.routes, ","))
+	}
+
+	return opts, nil
+}
+
+// serverLogger forwards the embedded server logs to the storer logger.
+type serverLogger struct {
+	core.Logger
+}
+
+func (l serverLogger) Noticef(format string, v ...interface{}) {
+	l.Infof(format, v...)
+}
+
+func (l serverLogger) Fatalf(format string, v ...interface{}) {
+	l.Errorf(format, v...)
+}
+
+func (l serverLogger) Tracef(format string, v ...interface{}) {
+	l.Debugf(format, v...)
+}
+
+// newEmbeddedServer starts the JetStream enabled server in this process and
+// waits until it accepts the connections. The clustered servers also wait for
+// the JetStream meta leader election, the buckets can't be created before.
+func newEmbeddedServer(cfg embeddedConfiguration, logger core.Logger) (*server.Server, error) {
+	opts, err := cfg.serverOptions()
+	if err != nil {
+		return nil, err
+	}
+
+	ns, err := server.NewServer(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	ns.SetLoggerV2(serverLogger{logger}, false, false, false)
+
+	go ns.Start()
+
+	if !ns.ReadyForConnections(embeddedStartupTimeout) {
+		ns.Shutdown()
+
+		return nil, errEmbeddedNotReady
+	}
+
+	if cfg.clusterListen != "" {
+		deadline := time.Now().Add(embeddedStartupTimeout)
+
+		for !ns.JetStreamIsCurrent() {
+			if time.Now().After(deadline) {
+				ns.Shutdown()
+
+				return nil, errEmbeddedNotReady
+			}
+
+			time.Sleep(100 * time.Millisecond)
+		}
+	}
+
+	logger.Info("Embedded Nats is ready for this node.")
+
+	return ns, nil
+}
+
+// retry runs the bucket creation until it succeeds or the startup timeout
+// expires, the clustered server peers may still be joining.
+func (cfg embeddedConfiguration) retry(create func() error) error {
+	if cfg.clusterListen == "" {
+		return create()
+	}
+
+	deadline := time.Now().Add(embeddedStartupTimeout)
+
+	for {
+		err := create()
+		if err == nil || time.Now().After(deadline) {
+			return err
+		}
+
+		time.Sleep(250 * time.Millisecond)
+	}
+}
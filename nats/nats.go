@@ -2,18 +2,17 @@ package nats
 
 import (
 	"bytes"
-	"encoding/gob"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"net/http"
-	"regexp"
 	"strconv"
 	"strings"
 	"time"
 
 	"dario.cat/mergo"
 	"github.com/darkweak/storages/core"
+	"github.com/nats-io/nats-server/v2/server"
 	nats "github.com/nats-io/nats.go"
 	lz4 "github.com/pierrec/lz4/v4"
 )
@@ -25,11 +24,16 @@ type Nats struct {
 	bucket string
 	stale  time.Duration
 	logger core.Logger
-}
 
-type item struct {
-	invalidAt time.Time
-	value     []byte
+	objects         nats.ObjectStore
+	objectThreshold int
+
+	conn *nats.Conn
+	// The in-process server started with the local mode.
+	server *server.Server
+
+	stopSweep chan struct{}
+	sweepDone chan struct{}
 }
 
 func sanitizeProperties(configMap map[string]interface{}) map[string]interface{} {
@@ -111,6 +115,7 @@ func sanitizeProperties(configMap map[string]interface{}) map[string]interface{}
 func Factory(natsConfiguration core.CacheProvider, logger core.Logger, stale time.Duration) (core.Storer, error) {
 	natsOptions := nats.GetDefaultOptions()
 	bucketName := "souin-bucket"
+	ttlConfig := parseTTLConfiguration(natsConfiguration.Configuration)
 
 	if natsConfiguration.Configuration != nil {
 		var parsedNats nats.Options
@@ -133,34 +138,96 @@ func Factory(natsConfiguration core.CacheProvider, logger core.Logger, stale tim
 		natsOptions.Servers = strings.Split(natsConfiguration.URL, ",")
 	}
 
+	objectConfig := parseObjectStoreConfiguration(natsConfiguration.Configuration, bucketName)
+
 	if len(natsOptions.Servers) == 0 {
 		natsOptions.Servers = []string{nats.DefaultURL}
 	}
 
+	embeddedConfig, embedded := parseEmbeddedConfiguration(natsConfiguration.Configuration)
+
+	var embeddedServer *server.Server
+
+	if embedded {
+		logger.Debug("Nats mode is local, starting the embedded Nats server")
+
+		ns, err := newEmbeddedServer(embeddedConfig, logger)
+		if err != nil {
+			logger.Error("Impossible to setup the embedded Nats server.", err)
+
+			return nil, err
+		}
+
+		embeddedServer = ns
+		natsOptions.InProcessServer = ns
+	}
+
 	natsConn, err := natsOptions.Connect()
 	if err != nil {
 		logger.Error("Impossible to connect to the Nats DB.", err)
 
+		if embeddedServer != nil {
+			embeddedServer.Shutdown()
+		}
+
 		return nil, err
 	}
 
+	provider := &Nats{
+		bucket: bucketName,
+		logger: logger,
+		stale:  stale,
+		conn:   natsConn,
+		server: embeddedServer,
+	}
+
 	stream, err := natsConn.JetStream()
 	if err != nil {
 		logger.Error("Impossible to instantiate the Nats DB.", err)
+		provider.release()
 
 		return nil, err
 	}
 
-	_, err = stream.CreateKeyValue(&nats.KeyValueConfig{
-		Bucket: bucketName,
+	err = embeddedConfig.retry(func() error {
+		_, err := stream.CreateKeyValue(&nats.KeyValueConfig{
+			Bucket:   bucketName,
+			TTL:      ttlConfig.ttl,
+			Storage:  embeddedConfig.storage,
+			Replicas: embeddedConfig.replicas,
+		})
+
+		return err
 	})
 	if err != nil {
 		logger.Error("Impossible to create the Nats bucket %s.", err, bucketName)
+		provider.release()
 
 		return nil, err
 	}
 
-	return &Nats{jsCtx: stream, bucket: bucketName, logger: logger, stale: stale}, nil
+	var objects nats.ObjectStore
+
+	var threshold int
+
+	err = embeddedConfig.retry(func() (err error) {
+		objects, threshold, err = newObjectStore(stream, objectConfig, ttlConfig.ttl, embeddedConfig, natsConn.MaxPayload())
+
+		return err
+	})
+	if err != nil {
+		logger.Errorf("Impossible to create the Nats object store %s: %v", objectConfig.bucket, err)
+		provider.release()
+
+		return nil, err
+	}
+
+	provider.jsCtx = stream
+	provider.objects = objects
+	provider.objectThreshold = threshold
+	provider.startSweep(ttlConfig.sweepInterval)
+
+	return provider, nil
 }
 
 // Name returns the storer name.
@@ -187,11 +254,29 @@ func (provider *Nats) MapKeys(prefix string) map[string]string {
 		return keys
 	}
 
+	now := time.Now()
+
 	for _, key := range keysList {
-		if strings.HasPrefix(key, prefix) {
-			val, _ := keyvalue.Get(key)
-			keys[strings.TrimPrefix(key, prefix)] = string(val.Value())
+		if !strings.HasPrefix(key, prefix) {
+			continue
+		}
+
+		entry, err := keyvalue.Get(key)
+		if err != nil {
+			continue
+		}
+
+		env, err := decodeEnvelope(entry.Value())
+		if err != nil || expired(env.expiresAt, now) {
+			continue
+		}
+
+		value, err := provider.load(entry.Key(), env)
+		if err != nil {
+			continue
 		}
+
+		keys[strings.TrimPrefix(key, prefix)] = string(value)
 	}
 
 	return keys
@@ -216,7 +301,7 @@ func (provider *Nats) Get(key string) []byte {
 		return nil
 	}
 
-	value, err := keyvalue.Get(key)
+	entry, err := keyvalue.Get(key)
 	if err != nil && !errors.Is(err, nats.ErrKeyNotFound) {
 		provider.logger.Errorf("Impossible to get the key %s in Nats: %v", key, err)
 
@@ -225,37 +310,39 @@ func (provider *Nats) Get(key string) []byte {
 		return nil
 	}
 
-	var res item
-
-	err = gob.NewDecoder(bytes.NewBuffer(value.Value())).Decode(&res)
+	env, err := decodeEnvelope(entry.Value())
 	if err != nil {
-		return value.Value()
+		provider.logger.Errorf("Impossible to decode the key %s in Nats: %v", key, err)
+
+		return nil
 	}
 
-	if res.invalidAt.After(time.Now()) {
-		return res.value
+	if expired(env.expiresAt, time.Now()) {
+		provider.purgeExpired(keyvalue, entry, env)
+
+		return nil
 	}
 
-	_ = keyvalue.Delete(key)
+	value, err := provider.load(key, env)
+	if err != nil {
+		provider.logger.Errorf("Impossible to get the object %s in Nats: %v", key, err)
+
+		return nil
+	}
 
-	return value.Value()
+	return value
 }
 
 // GetMultiLevel tries to load the key and check if one of linked keys is a fresh/stale candidate.
 func (provider *Nats) GetMultiLevel(key string, req *http.Request, validator *core.Revalidator) (fresh *http.Response, stale *http.Response) {
-	keyvalue, err := provider.jsCtx.KeyValue(provider.bucket)
-	if err != nil {
-		return
-	}
-
-	value, err := keyvalue.Get(core.MappingKeyPrefix + key)
-	if err != nil {
+	value := provider.Get(core.MappingKeyPrefix + key)
+	if value == nil {
 		provider.logger.Debugf("Impossible to get the mapping key %s in Nats", core.MappingKeyPrefix+key)
 
 		return
 	}
 
-	fresh, stale, _ = core.MappingElection(provider, value.Value(), req, validator, provider.logger)
+	fresh, stale, _ = core.MappingElection(provider, value, req, validator, provider.logger)
 
 	return
 }
@@ -271,55 +358,80 @@ func (provider *Nats) SetMultiLevel(baseKey, variedKey string, value []byte, var
 		return err
 	}
 
-	property := item{
-		invalidAt: now.Add(duration + provider.stale),
-		value:     compressed.Bytes(),
+	if err := provider.put(variedKey, compressed.Bytes(), duration+provider.stale); err != nil {
+		return err
 	}
 
-	buf := new(bytes.Buffer)
+	mappingKey := core.MappingKeyPrefix + baseKey
+	r := provider.Get(mappingKey)
 
-	err := gob.NewEncoder(buf).Encode(property)
+	val, err := core.MappingUpdater(variedKey, r, provider.logger, now, now.Add(duration), now.Add(duration+provider.stale), variedHeaders, etag, realKey)
 	if err != nil {
-		provider.logger.Errorf("Impossible to encode the key %s in Nats: %v", variedKey, err)
+		provider.logger.Errorf("Impossible to update the mapping key %s in Nats: %v", mappingKey, err)
 
-		return nil
+		return err
 	}
 
-	keyvalue, err := provider.jsCtx.KeyValue(provider.bucket)
+	return provider.put(mappingKey, val, mappingTTL(val, now))
+}
+
+// mappingTTL returns the time left until the furthest stale time of the
+// mapping entries, the mapping must outlive every variant it indexes.
+func mappingTTL(mapping []byte, now time.Time) time.Duration {
+	decoded, err := core.DecodeMapping(mapping)
 	if err != nil {
-		return err
+		return 0
 	}
 
-	_, err = keyvalue.Put(variedKey, buf.Bytes())
-	if err != nil {
-		provider.logger.Errorf("Impossible to set value into Nats for the key %s, %v", variedKey, err)
+	furthest := now
 
-		return err
+	for _, entry := range decoded.GetMapping() {
+		if stale := entry.GetStaleTime().AsTime(); stale.After(furthest) {
+			furthest = stale
+		}
 	}
 
-	mappingKey := core.MappingKeyPrefix + baseKey
-	r := provider.Get(mappingKey)
-
-	val, err := core.MappingUpdater(variedKey, r, provider.logger, now, now.Add(duration), now.Add(duration+provider.stale), variedHeaders, etag, realKey)
-	if err != nil {
-		provider.logger.Errorf("Impossible to update the mapping key %s in Nats: %v", mappingKey, err)
+	return furthest.Sub(now)
+}
 
-		return err
+// Set method will store the response in Nats provider.
+func (provider *Nats) Set(key string, value []byte, duration time.Duration) error {
+	if duration == -1 {
+		duration = 0
+	} else {
+		duration += provider.stale
 	}
 
-	return provider.Set(mappingKey, val, duration+provider.stale)
+	return provider.put(key, value, duration)
 }
 
-// Set method will store the response in Nats provider.
-func (provider *Nats) Set(key string, value []byte, _ time.Duration) error {
+// put stores the value wrapped in its envelope, it never expires when the ttl isn't positive.
+func (provider *Nats) put(key string, value []byte, ttl time.Duration) error {
 	keyvalue, err := provider.jsCtx.KeyValue(provider.bucket)
 	if err != nil {
 		return err
 	}
 
-	_, err = keyvalue.Put(key, value)
+	env := envelope{value: value}
+	if ttl > 0 {
+		env.expiresAt = time.Now().Add(ttl)
+	}
+
+	// The object is written before the pointer so a reader never follows a
+	// pointer to a missing object.
+	if provider.external(value) {
+		if _, err = provider.objects.PutBytes(key, value); err != nil {
+			provider.logger.Errorf("Impossible to set the object into Nats for the key %s, %v", key, err)
+
+			return err
+		}
+
+		env.value, env.external = nil, true
+	}
+
+	_, err = keyvalue.Put(key, encodeEnvelope(env))
 	if err != nil {
-		provider.logger.Errorf("Impossible to set value into Nats, %v", err)
+		provider.logger.Errorf("Impossible to set value into Nats for the key %s, %v", key, err)
 	}
 
 	return err
@@ -335,11 +447,13 @@ func (provider *Nats) Delete(key string) {
 	}
 
 	_ = keyvalue.Purge(key)
+
+	provider.deleteObject(key)
 }
 
 // DeleteMany method will delete the responses in Nats provider if exists corresponding to the regex key param.
 func (provider *Nats) DeleteMany(key string) {
-	rgKey, err := regexp.Compile(key)
+	matcher, err := core.ParseMatcher(key)
 	if err != nil {
 		return
 	}
@@ -350,15 +464,15 @@ func (provider *Nats) DeleteMany(key string) {
 	}
 
 	keys, err := keyvalue.Keys()
-	if err != nil {
-		return
-	}
-
-	for _, key := range keys {
-		if rgKey.MatchString(key) {
-			_ = keyvalue.Purge(key)
+	if err == nil {
+		for _, key := range keys {
+			if matcher.Match(key) {
+				_ = keyvalue.Purge(key)
+			}
 		}
 	}
+
+	provider.deleteObjects(matcher)
 }
 
 // Init method will.
@@ -368,5 +482,20 @@ func (provider *Nats) Init() error {
 
 // Reset method will reset or close provider.
 func (provider *Nats) Reset() error {
+	provider.stopSweeping()
+	provider.release()
+
 	return nil
 }
+
+// release closes the connection, then shuts the embedded server down.
+func (provider *Nats) release() {
+	if provider.conn != nil {
+		provider.conn.Close()
+	}
+
+	if provider.server != nil {
+		provider.server.Shutdown()
+		provider.server.WaitForShutdown()
+	}
+}
@@ -1,11 +1,18 @@
 package nats_test
 
 import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/darkweak/storages/core"
 	"github.com/darkweak/storages/nats"
+	natsgo "github.com/nats-io/nats.go"
 	"go.uber.org/zap"
 )
 
@@ -15,10 +22,54 @@ const (
 	baseValue      = "My first data"
 )
 
+const embeddedURL = "nats://127.0.0.1:14222"
+
+var embeddedInstance core.Storer
+
+// getNatsInstance returns the provider running the embedded server shared by
+// the tests, the other providers connect to it.
 func getNatsInstance() (core.Storer, error) {
+	if embeddedInstance != nil {
+		return embeddedInstance, nil
+	}
+
 	z, _ := zap.NewDevelopment()
 
-	return nats.Factory(core.CacheProvider{}, z.Sugar(), 0)
+	instance, err := nats.Factory(core.CacheProvider{
+		Configuration: map[string]interface{}{
+			"mode":   "local",
+			"listen": "127.0.0.1:14222",
+		},
+	}, z.Sugar(), 0)
+	if err != nil {
+		return nil, err
+	}
+
+	embeddedInstance = instance
+
+	return instance, nil
+}
+
+func getNatsClientInstance(t *testing.T, configuration map[string]interface{}) core.Storer {
+	t.Helper()
+
+	if _, err := getNatsInstance(); err != nil {
+		t.Fatal("Impossible to start the embedded Nats server", err)
+	}
+
+	z, _ := zap.NewDevelopment()
+	configuration["Url"] = embeddedURL
+
+	client, err := nats.Factory(core.CacheProvider{Configuration: configuration}, z.Sugar(), 0)
+	if err != nil {
+		t.Fatal("Impossible to instantiate the Nats provider", err)
+	}
+
+	t.Cleanup(func() {
+		_ = client.Reset()
+	})
+
+	return client
 }
 
 func TestNatsConnectionFactory(t *testing.T) {
@@ -105,3 +156,215 @@ func TestNats_Init(t *testing.T) {
 		t.Error("Impossible to init Nats provider")
 	}
 }
+
+func TestNats_SetRequestInCache_Expired(t *testing.T) {
+	key := "MyExpiredKey"
+	client, _ := getNatsInstance()
+	_ = client.Set(key, []byte("Hello world"), 500*time.Millisecond)
+	time.Sleep(1 * time.Second)
+
+	if 0 < len(client.Get(key)) {
+		t.Errorf("Key %s should be expired", key)
+	}
+}
+
+func TestNats_Sweep(t *testing.T) {
+	client := getNatsClientInstance(t, map[string]interface{}{
+		"keyvalue":      "souin-sweep-bucket",
+		"SweepInterval": "200ms",
+	})
+
+	_ = client.Set("SweptKey", []byte("Hello world"), 300*time.Millisecond)
+	_ = client.Set("PersistentKey", []byte("Hello world"), -1)
+	time.Sleep(1 * time.Second)
+
+	keys := client.ListKeys()
+	if len(keys) != 1 || keys[0] != "PersistentKey" {
+		t.Errorf("Only the persistent key should remain after the sweep, %v provided", keys)
+	}
+}
+
+func TestNats_MappingOutlivesShortVariant(t *testing.T) {
+	client, _ := getNatsInstance()
+	response := []byte("HTTP/1.1 200 OK\r\nContent-Length: 13\r\n\r\n" + baseValue)
+
+	_ = client.SetMultiLevel("Mapping", "Mapping-long", response, http.Header{"Accept": {"text/html"}}, "", 10*time.Second, "Mapping-long")
+	_ = client.SetMultiLevel("Mapping", "Mapping-short", response, http.Header{"Accept": {"text/plain"}}, "", 300*time.Millisecond, "Mapping-short")
+	time.Sleep(time.Second)
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Accept", "text/html")
+
+	// The short variant must not expire the mapping indexing the long one.
+	if fresh, _ := client.GetMultiLevel("Mapping", req, &core.Revalidator{}); fresh == nil {
+		t.Error("The long lived variant should still be reachable")
+	}
+}
+
+func getObjectStore(t *testing.T, bucket string) natsgo.ObjectStore {
+	t.Helper()
+
+	if _, err := getNatsInstance(); err != nil {
+		t.Fatal("Impossible to start the embedded Nats server", err)
+	}
+
+	conn, err := natsgo.Connect(embeddedURL)
+	if err != nil {
+		t.Fatal("Impossible to connect to Nats", err)
+	}
+
+	t.Cleanup(conn.Close)
+
+	stream, _ := conn.JetStream()
+
+	objects, err := stream.ObjectStore(bucket)
+	if err != nil {
+		t.Fatal("The object store should exist", err)
+	}
+
+	return objects
+}
+
+func TestNats_LargeValueInObjectStore(t *testing.T) {
+	key := "MyLargeKey"
+	client, _ := getNatsInstance()
+	objects := getObjectStore(t, "souin-bucket-objects")
+	value := bytes.Repeat([]byte("A"), 2*1024*1024)
+
+	if err := client.Set(key, value, 20*time.Second); err != nil {
+		t.Fatal("The large value should be stored", err)
+	}
+
+	if !bytes.Equal(client.Get(key), value) {
+		t.Errorf("Key %s should be equals to the large value", key)
+	}
+
+	if _, err := objects.GetInfo(key); err != nil {
+		t.Errorf("The object %s should exist: %v", key, err)
+	}
+
+	client.Delete(key)
+
+	if 0 < len(client.Get(key)) {
+		t.Errorf("Key %s should not exist", key)
+	}
+
+	if _, err := objects.GetInfo(key); !errors.Is(err, natsgo.ErrObjectNotFound) {
+		t.Errorf("The object %s should be deleted: %v", key, err)
+	}
+}
+
+func TestNats_LargeValueExpiry(t *testing.T) {
+	client := getNatsClientInstance(t, map[string]interface{}{
+		"keyvalue":             "souin-large-bucket",
+		"ObjectStoreThreshold": 16,
+		"SweepInterval":        "200ms",
+	})
+
+	objects := getObjectStore(t, "souin-large-bucket-objects")
+
+	_ = client.Set("ExpiredLargeKey", []byte("A value above the threshold"), 300*time.Millisecond)
+	_ = client.Set("SmallKey", []byte("Small"), -1)
+	_ = client.Set("RewrittenKey", []byte("A value above the threshold"), -1)
+	_ = client.Set("RewrittenKey", []byte("Small"), -1)
+
+	time.Sleep(1 * time.Second)
+
+	if _, err := objects.GetInfo("ExpiredLargeKey"); !errors.Is(err, natsgo.ErrObjectNotFound) {
+		t.Errorf("The expired object should be swept: %v", err)
+	}
+
+	if _, err := objects.GetInfo("RewrittenKey"); !errors.Is(err, natsgo.ErrObjectNotFound) {
+		t.Errorf("The object without pointer should be swept: %v", err)
+	}
+
+	if string(client.Get("RewrittenKey")) != "Small" {
+		t.Errorf("Key RewrittenKey should be equals to Small")
+	}
+}
+
+func TestNats_EmbeddedCluster(t *testing.T) {
+	z, _ := zap.NewDevelopment()
+	routes := []interface{}{"nats://127.0.0.1:16222", "nats://127.0.0.1:16223", "nats://127.0.0.1:16224"}
+	nodes := make([]core.Storer, len(routes))
+	errs := make(chan error, len(routes))
+
+	var wg sync.WaitGroup
+
+	for i := range routes {
+		wg.Add(1)
+
+		go func(i int) {
+			defer wg.Done()
+
+			node, err := nats.Factory(core.CacheProvider{
+				Configuration: map[string]interface{}{
+					"mode":        "local",
+					"server_name": fmt.Sprintf("souin-%d", i),
+					"storage":     "file",
+					"store_dir":   t.TempDir(),
+					"replicas":    3,
+					"keyvalue":    "souin-cluster-bucket",
+					"cluster": map[string]interface{}{
+						"name":   "souin",
+						"listen": fmt.Sprintf("127.0.0.1:%d", 16222+i),
+						"routes": routes,
+					},
+				},
+			}, z.Sugar(), 0)
+			if err != nil {
+				errs <- err
+
+				return
+			}
+
+			nodes[i] = node
+		}(i)
+	}
+
+	wg.Wait()
+	close(errs)
+
+	t.Cleanup(func() {
+		for _, node := range nodes {
+			if node != nil {
+				_ = node.Reset()
+			}
+		}
+	})
+
+	for err := range errs {
+		t.Fatal("Impossible to start the embedded Nats cluster", err)
+	}
+
+	_ = nodes[0].Set("ClusterKey", []byte(baseValue), -1)
+
+	if string(nodes[2].Get("ClusterKey")) != baseValue {
+		t.Errorf("Key ClusterKey should be replicated to every node")
+	}
+}
+
+func TestNats_EmbeddedFactoryFailureReleasesServer(t *testing.T) {
+	z, _ := zap.NewDevelopment()
+	configuration := func(objectStore string) core.CacheProvider {
+		return core.CacheProvider{
+			Configuration: map[string]interface{}{
+				"mode":        "local",
+				"listen":      "127.0.0.1:14322",
+				"objectstore": objectStore,
+			},
+		}
+	}
+
+	if _, err := nats.Factory(configuration("invalid object store"), z.Sugar(), 0); err == nil {
+		t.Fatal("The invalid object store name should be rejected")
+	}
+
+	// The failed factory released the listen address.
+	client, err := nats.Factory(configuration("souin-objects"), z.Sugar(), 0)
+	if err != nil {
+		t.Fatal("The embedded server should start again", err)
+	}
+
+	_ = client.Reset()
+}
@@ -0,0 +1,145 @@
+package nats
+
+import (
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/darkweak/storages/core"
+	nats "github.com/nats-io/nats.go"
+)
+
+// Room left in the server max payload for the key, the headers and the envelope.
+const objectStoreMargin = 4 * 1024
+
+type objectStoreConfiguration struct {
+	bucket string
+	// Size above which the values are stored in the object store, 0 derives
+	// it from the server max payload and a negative one disables the store.
+	threshold int
+}
+
+func parseObjectStoreConfiguration(configuration interface{}, bucketName string) objectStoreConfiguration {
+	cfg := objectStoreConfiguration{bucket: bucketName + "-objects"}
+
+	configMap, ok := configuration.(map[string]interface{})
+	if !ok {
+		return cfg
+	}
+
+	if v, ok := configMap["objectstore"].(string); ok && v != "" {
+		cfg.bucket = v
+	}
+
+	switch v := configMap["ObjectStoreThreshold"].(type) {
+	case int:
+		cfg.threshold = v
+	case float64:
+		cfg.threshold = int(v)
+	case string:
+		cfg.threshold, _ = strconv.Atoi(v)
+	}
+
+	return cfg
+}
+
+// newObjectStore creates the object store holding the values too large for
+// the key-value bucket, it returns a nil store when it's disabled.
+func newObjectStore(
+	stream nats.JetStreamContext,
+	cfg objectStoreConfiguration,
+	ttl time.Duration,
+	embedded embeddedConfiguration,
+	maxPayload int64,
+) (nats.ObjectStore, int, error) {
+	if cfg.threshold < 0 {
+		return nil, 0, nil
+	}
+
+	threshold := cfg.threshold
+	if threshold == 0 {
+		threshold = int(maxPayload) - objectStoreMargin
+	}
+
+	objects, err := stream.CreateObjectStore(&nats.ObjectStoreConfig{
+		Bucket:   cfg.bucket,
+		TTL:      ttl,
+		Storage:  embedded.storage,
+		Replicas: embedded.replicas,
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return objects, threshold, nil
+}
+
+func (provider *Nats) external(value []byte) bool {
+	return provider.objects != nil && len(value) > provider.objectThreshold
+}
+
+// load returns the value of the envelope, following the pointer to the
+// object store when the value is stored there.
+func (provider *Nats) load(key string, env envelope) ([]byte, error) {
+	if !env.external {
+		return env.value, nil
+	}
+
+	if provider.objects == nil {
+		return nil, nats.ErrObjectNotFound
+	}
+
+	return provider.objects.GetBytes(key)
+}
+
+func (provider *Nats) deleteObject(key string) {
+	if provider.objects == nil {
+		return
+	}
+
+	if err := provider.objects.Delete(key); err != nil && !errors.Is(err, nats.ErrObjectNotFound) {
+		provider.logger.Errorf("Impossible to delete the object %s in Nats: %v", key, err)
+	}
+}
+
+// deleteObjects removes the objects matching the matcher.
+func (provider *Nats) deleteObjects(matcher core.Matcher) {
+	if provider.objects == nil {
+		return
+	}
+
+	objects, err := provider.objects.List()
+	if err != nil {
+		return
+	}
+
+	for _, object := range objects {
+		if matcher.Match(object.Name) {
+			provider.deleteObject(object.Name)
+		}
+	}
+}
+
+// sweepObjects removes the objects no key points to anymore, the values
+// rewritten inline or the pointers purged concurrently. The objects written
+// since the sweep started are kept, their pointer may not be written yet.
+func (provider *Nats) sweepObjects(pointed map[string]bool, startedAt time.Time) {
+	if provider.objects == nil {
+		return
+	}
+
+	objects, err := provider.objects.List()
+	if err != nil {
+		if !errors.Is(err, nats.ErrNoObjectsFound) {
+			provider.logger.Errorf("Impossible to list the objects to sweep in Nats: %v", err)
+		}
+
+		return
+	}
+
+	for _, object := range objects {
+		if !pointed[object.Name] && object.ModTime.Before(startedAt) {
+			provider.deleteObject(object.Name)
+		}
+	}
+}
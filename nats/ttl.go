@@ -0,0 +1,224 @@
+package nats
+
+import (
+	"encoding/binary"
+	"errors"
+	"time"
+
+	nats "github.com/nats-io/nats.go"
+)
+
+const (
+	envelopeMagic   byte = 0xC5
+	envelopeVersion byte = 2
+	// Magic byte, version, flags and expiry.
+	envelopeHeaderSize = 11
+	// The first version has no flags.
+	envelopeV1HeaderSize = 10
+
+	// The value is stored in the object store under the same key.
+	flagExternal byte = 1 << 0
+
+	defaultSweepInterval = time.Minute
+)
+
+var errUnknownEnvelopeVersion = errors.New("unknown nats envelope version")
+
+type ttlConfiguration struct {
+	// Maximum age of every value in the bucket, 0 keeps them until they expire.
+	ttl           time.Duration
+	sweepInterval time.Duration
+}
+
+func parseTTLConfiguration(configuration interface{}) ttlConfiguration {
+	cfg := ttlConfiguration{sweepInterval: defaultSweepInterval}
+
+	configMap, ok := configuration.(map[string]interface{})
+	if !ok {
+		return cfg
+	}
+
+	for property, target := range map[string]*time.Duration{
+		"TTL":           &cfg.ttl,
+		"SweepInterval": &cfg.sweepInterval,
+	} {
+		switch val := configMap[property].(type) {
+		case time.Duration:
+			*target = val
+		case float64:
+			*target = time.Duration(val)
+		case string:
+			if d, err := time.ParseDuration(val); err == nil {
+				*target = d
+			}
+		}
+	}
+
+	return cfg
+}
+
+type envelope struct {
+	value []byte
+	// Zero when the value never expires.
+	expiresAt time.Time
+	external  bool
+}
+
+// encodeEnvelope prefixes the value with the envelope header: a magic byte,
+// the envelope version, the flags and the expiry as big-endian unix
+// milliseconds, 0 when the value never expires.
+func encodeEnvelope(env envelope) []byte {
+	raw := make([]byte, envelopeHeaderSize, envelopeHeaderSize+len(env.value))
+	raw[0] = envelopeMagic
+	raw[1] = envelopeVersion
+
+	if env.external {
+		raw[2] |= flagExternal
+	}
+
+	if !env.expiresAt.IsZero() {
+		//nolint:gosec
+		binary.BigEndian.PutUint64(raw[3:envelopeHeaderSize], uint64(env.expiresAt.UnixMilli()))
+	}
+
+	return append(raw, env.value...)
+}
+
+// decodeEnvelope reads the envelope of any known version. The values stored
+// without envelope never expire.
+func decodeEnvelope(raw []byte) (envelope, error) {
+	if len(raw) < envelopeV1HeaderSize || raw[0] != envelopeMagic {
+		return envelope{value: raw}, nil
+	}
+
+	var env envelope
+
+	var expiry []byte
+
+	switch raw[1] {
+	case 1:
+		expiry, env.value = raw[2:envelopeV1HeaderSize], raw[envelopeV1HeaderSize:]
+	case envelopeVersion:
+		if len(raw) < envelopeHeaderSize {
+			return envelope{}, errUnknownEnvelopeVersion
+		}
+
+		env.external = raw[2]&flagExternal != 0
+		expiry, env.value = raw[3:envelopeHeaderSize], raw[envelopeHeaderSize:]
+	default:
+		return envelope{}, errUnknownEnvelopeVersion
+	}
+
+	//nolint:gosec
+	if ms := int64(binary.BigEndian.Uint64(expiry)); ms != 0 {
+		env.expiresAt = time.UnixMilli(ms)
+	}
+
+	return env, nil
+}
+
+func expired(expiresAt, now time.Time) bool {
+	return !expiresAt.IsZero() && !expiresAt.After(now)
+}
+
+// purgeExpired removes the entry and its object if it's still the last
+// revision of its key, a concurrent write wins over the expiry.
+func (provider *Nats) purgeExpired(keyvalue nats.KeyValue, entry nats.KeyValueEntry, env envelope) {
+	if err := keyvalue.Purge(entry.Key(), nats.LastRevision(entry.Revision())); err != nil {
+		return
+	}
+
+	if env.external {
+		provider.deleteObject(entry.Key())
+	}
+}
+
+// startSweep purges the expired keys in the background, it's a no-op when the
+// sweep interval isn't positive.
+func (provider *Nats) startSweep(interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+
+	provider.stopSweep = make(chan struct{})
+	provider.sweepDone = make(chan struct{})
+
+	go func() {
+		defer close(provider.sweepDone)
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-provider.stopSweep:
+				return
+			case <-ticker.C:
+				provider.sweep()
+			}
+		}
+	}()
+}
+
+func (provider *Nats) stopSweeping() {
+	if provider.stopSweep == nil {
+		return
+	}
+
+	select {
+	case <-provider.stopSweep:
+	default:
+		close(provider.stopSweep)
+	}
+
+	<-provider.sweepDone
+}
+
+// sweep purges every expired key of the bucket, then the objects no key points to.
+func (provider *Nats) sweep() {
+	keyvalue, err := provider.jsCtx.KeyValue(provider.bucket)
+	if err != nil {
+		return
+	}
+
+	// Taken before the listing, the objects written since then are kept.
+	now := time.Now()
+
+	keys, err := keyvalue.Keys()
+	if err != nil && !errors.Is(err, nats.ErrNoKeysFound) {
+		provider.logger.Errorf("Impossible to list the keys to sweep in Nats: %v", err)
+
+		return
+	}
+
+	purged := 0
+	pointed := map[string]bool{}
+
+	for _, key := range keys {
+		entry, err := keyvalue.Get(key)
+		if err != nil {
+			continue
+		}
+
+		env, err := decodeEnvelope(entry.Value())
+		if err != nil {
+			continue
+		}
+
+		if expired(env.expiresAt, now) {
+			provider.purgeExpired(keyvalue, entry, env)
+
+			purged++
+
+			continue
+		}
+
+		if env.external {
+			pointed[key] = true
+		}
+	}
+
+	provider.logger.Debugf("Swept %d expired keys from the Nats bucket %s", purged, provider.bucket)
+
+	provider.sweepObjects(pointed, now)
+}
@@ -0,0 +1,164 @@
+package nuts
+
+import (
+	"bufio"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/darkweak/storages/core"
+	"github.com/nutsdb/nutsdb"
+)
+
+const (
+	backupVersion = 1
+	// Number of entries written per transaction during a restore.
+	restoreBatchSize = 1000
+)
+
+var _ core.BackupStorer = (*Nuts)(nil)
+
+type backupHeader struct {
+	Version   int
+	CreatedAt int64
+}
+
+type backupEntry struct {
+	Key   []byte
+	Value []byte
+	// Unix time in seconds after which the entry is expired, 0 when persistent.
+	ExpiresAt int64
+}
+
+// Backup writes every live entry of the bucket to the writer with its
+// expiration. It runs in a read transaction so the provider keeps serving
+// requests.
+func (provider *Nuts) Backup(w io.Writer) error {
+	writer := bufio.NewWriter(w)
+	encoder := gob.NewEncoder(writer)
+
+	if err := encoder.Encode(backupHeader{Version: backupVersion, CreatedAt: time.Now().Unix()}); err != nil {
+		return err
+	}
+
+	err := provider.DB.View(func(tx *nutsdb.Tx) error {
+		keys, values, err := tx.GetAll(bucket)
+		if err != nil {
+			if errors.Is(err, nutsdb.ErrBucketNotFound) || errors.Is(err, nutsdb.ErrBucketEmpty) {
+				return nil
+			}
+
+			return err
+		}
+
+		now := time.Now().Unix()
+
+		for i, key := range keys {
+			ttl, err := tx.GetTTL(bucket, key)
+			if err != nil {
+				// Expired since the scan.
+				continue
+			}
+
+			entry := backupEntry{Key: key, Value: values[i]}
+			if ttl >= 0 {
+				entry.ExpiresAt = now + ttl
+			}
+
+			if err = encoder.Encode(entry); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		provider.logger.Errorf("Impossible to backup the Nuts DB, %v", err)
+
+		return err
+	}
+
+	return writer.Flush()
+}
+
+// Restore loads a backup written by Backup, the expired entries are skipped
+// and the others keep their remaining TTL.
+func (provider *Nuts) Restore(r io.Reader) error {
+	decoder := gob.NewDecoder(bufio.NewReader(r))
+
+	var header backupHeader
+	if err := decoder.Decode(&header); err != nil {
+		return err
+	}
+
+	if header.Version != backupVersion {
+		return fmt.Errorf("unsupported nuts backup version %d", header.Version)
+	}
+
+	_ = provider.DB.Update(func(tx *nutsdb.Tx) error {
+		return tx.NewBucket(nutsdb.DataStructureBTree, bucket)
+	})
+
+	batch := make([]backupEntry, 0, restoreBatchSize)
+
+	for {
+		var entry backupEntry
+		if err := decoder.Decode(&entry); err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+
+			return err
+		}
+
+		batch = append(batch, entry)
+		if len(batch) < restoreBatchSize {
+			continue
+		}
+
+		if err := provider.restoreBatch(batch); err != nil {
+			return err
+		}
+
+		batch = batch[:0]
+	}
+
+	return provider.restoreBatch(batch)
+}
+
+// restoreBatch writes the entries in a single transaction.
+func (provider *Nuts) restoreBatch(entries []backupEntry) error {
+	if len(entries) == 0 {
+		return nil
+	}
+
+	now := time.Now().Unix()
+
+	err := provider.DB.Update(func(tx *nutsdb.Tx) error {
+		for _, entry := range entries {
+			ttl := nutsdb.Persistent
+
+			if entry.ExpiresAt != 0 {
+				if entry.ExpiresAt <= now {
+					continue
+				}
+
+				//nolint:gosec
+				ttl = uint32(entry.ExpiresAt - now)
+			}
+
+			if err := tx.Put(bucket, entry.Key, entry.Value, ttl); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		provider.logger.Errorf("Impossible to restore the Nuts DB, %v", err)
+	}
+
+	return err
+}
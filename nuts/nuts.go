@@ -7,7 +7,6 @@ import (
 	"fmt"
 	"net/http"
 	"os"
-	"regexp"
 	"strconv"
 	"strings"
 	"sync"
@@ -374,9 +373,9 @@ func (provider *Nuts) Delete(key string) {
 
 // DeleteMany method will delete the responses in Nuts provider if exists corresponding to the regex key param.
 func (provider *Nuts) DeleteMany(key string) {
-	rgKey, err := regexp.Compile(key)
+	matcher, err := core.ParseMatcher(key)
 	if err != nil {
-		provider.logger.Errorf("The key %s is not a valid regexp: %v", key, err)
+		provider.logger.Errorf("The key %s is not a valid pattern: %v", key, err)
 
 		return
 	}
@@ -386,7 +385,7 @@ func (provider *Nuts) DeleteMany(key string) {
 			return err
 		} else {
 			for _, entry := range entries {
-				if rgKey.Match(entry) {
+				if matcher.Match(string(entry)) {
 					_ = ntx.Delete(bucket, entry)
 				}
 			}
@@ -1,6 +1,7 @@
 package nuts_test
 
 import (
+	"bytes"
 	"testing"
 	"time"
 
@@ -107,3 +108,26 @@ func TestNuts_Init(t *testing.T) {
 		t.Error("Impossible to init Nuts provider")
 	}
 }
+
+func TestNuts_BackupRestore(t *testing.T) {
+	source, _ := nuts.Factory(core.CacheProvider{Path: t.TempDir()}, zap.NewNop().Sugar(), 0)
+	target, _ := nuts.Factory(core.CacheProvider{Path: t.TempDir()}, zap.NewNop().Sugar(), 0)
+
+	_ = source.Set("Expiring", []byte(baseValue), 20*time.Second)
+	_ = source.Set("Persistent", []byte(baseValue), 0)
+
+	backup := new(bytes.Buffer)
+	if err := source.(core.BackupStorer).Backup(backup); err != nil {
+		t.Fatalf("Impossible to backup the Nuts provider: %v", err)
+	}
+
+	if err := target.(core.BackupStorer).Restore(backup); err != nil {
+		t.Fatalf("Impossible to restore the Nuts provider: %v", err)
+	}
+
+	for _, key := range []string{"Expiring", "Persistent"} {
+		if string(target.Get(key)) != baseValue {
+			t.Errorf("The key %s should be restored, got %s", key, target.Get(key))
+		}
+	}
+}
@@ -21,15 +21,40 @@ import (
 
 // Olric provider type.
 type Olric struct {
-	olric.Client
-	dm            *sync.Pool
+	supervisor    *core.Supervisor[*connection]
 	stale         time.Duration
 	logger        core.Logger
 	addresses     []string
-	reconnecting  bool
 	configuration config.Client
 }
 
+// connection is an Olric client with its pool of DMaps, replaced together on reconnection.
+type connection struct {
+	olric.Client
+	dm *sync.Pool
+}
+
+func newConnection(client olric.Client) *connection {
+	return &connection{
+		Client: client,
+		dm: &sync.Pool{
+			New: func() interface{} {
+				// The initial dial may have failed, the client is set on reconnection.
+				if client == nil {
+					return nil
+				}
+
+				dmap, err := client.NewDMap("souin-map")
+				if err != nil {
+					return nil
+				}
+
+				return dmap
+			},
+		},
+	}
+}
+
 func tryToLoadConfiguration(olricInstance *config.Config, olricConfiguration core.CacheProvider, logger core.Logger) (*config.Config, bool) {
 	var err error
 
@@ -126,9 +151,9 @@ func Factory(olricConfiguration core.CacheProvider, logger core.Logger, stale ti
 					return nil, err
 				}
 
+				// The embedded instance runs in this process, there is nothing to reconnect.
 				return &Olric{
-					Client:        client,
-					dm:            nil,
+					supervisor:    core.NewSupervisor[*connection](newConnection(client), nil, nil, logger),
 					stale:         stale,
 					logger:        logger,
 					configuration: config.Client{},
@@ -138,19 +163,61 @@ func Factory(olricConfiguration core.CacheProvider, logger core.Logger, stale ti
 		}
 	}
 
-	client, err := olric.NewClusterClient(strings.Split(olricConfiguration.URL, ","))
-	if err != nil {
+	addresses := strings.Split(olricConfiguration.URL, ",")
+	configuration := config.Client{}
+
+	var client olric.Client
+
+	if c, err := olric.NewClusterClient(addresses); err != nil {
 		logger.Errorf("Impossible to connect to Olric, %v", err)
+	} else {
+		client = c
+	}
+
+	dial := func() (*connection, error) {
+		c, err := olric.NewClusterClient(addresses, olric.WithConfig(&configuration))
+		if err != nil {
+			return nil, err
+		}
+
+		return newConnection(c), nil
 	}
 
-	return &Olric{
-		Client:        client,
-		dm:            nil,
+	provider := &Olric{
+		supervisor: core.NewSupervisor(newConnection(client), dial, func(previous *connection) {
+			if previous.Client != nil {
+				_ = previous.Close(context.Background())
+			}
+		}, logger),
 		stale:         stale,
 		logger:        logger,
-		configuration: config.Client{},
-		addresses:     strings.Split(olricConfiguration.URL, ","),
-	}, nil
+		configuration: configuration,
+		addresses:     addresses,
+	}
+
+	if client == nil {
+		provider.Reconnect()
+	}
+
+	return provider, nil
+}
+
+// Client returns the current Olric client, it's replaced on reconnection.
+func (provider *Olric) Client() olric.Client {
+	return provider.supervisor.Client().Client
+}
+
+func (provider *Olric) dmaps() *sync.Pool {
+	return provider.supervisor.Client().dm
+}
+
+// dmap takes a DMap from the pool of the current connection with the pool to
+// put it back in, the DMap is nil while no client is connected.
+func (provider *Olric) dmap() (olric.DMap, *sync.Pool) {
+	pool := provider.dmaps()
+	dm, _ := pool.Get().(olric.DMap)
+
+	return dm, pool
 }
 
 // Name returns the storer name.
@@ -165,20 +232,24 @@ func (provider *Olric) Uuid() string {
 
 // ListKeys method returns the list of existing keys.
 func (provider *Olric) ListKeys() []string {
-	if provider.reconnecting {
+	if provider.supervisor.Reconnecting() {
 		provider.logger.Error("Impossible to list the olric keys while reconnecting.")
 
 		return []string{}
 	}
 
-	dm := provider.dm.Get().(olric.DMap)
-	defer provider.dm.Put(dm)
+	dm, pool := provider.dmap()
+	if dm == nil {
+		provider.logger.Error("Impossible to get an olric DMap without client.")
+
+		return []string{}
+	}
+
+	defer pool.Put(dm)
 
 	records, err := dm.Scan(context.Background(), olric.Match("^"+core.MappingKeyPrefix))
 	if err != nil {
-		if !provider.reconnecting {
-			go provider.Reconnect()
-		}
+		provider.Reconnect()
 
 		provider.logger.Error("An error occurred while trying to list keys in Olric: %s\n", err)
 
@@ -203,20 +274,24 @@ func (provider *Olric) ListKeys() []string {
 
 // MapKeys method returns the map of existing keys.
 func (provider *Olric) MapKeys(prefix string) map[string]string {
-	if provider.reconnecting {
+	if provider.supervisor.Reconnecting() {
 		provider.logger.Error("Impossible to list the olric keys while reconnecting.")
 
 		return map[string]string{}
 	}
 
-	dm := provider.dm.Get().(olric.DMap)
-	defer provider.dm.Put(dm)
+	dm, pool := provider.dmap()
+	if dm == nil {
+		provider.logger.Error("Impossible to get an olric DMap without client.")
+
+		return map[string]string{}
+	}
+
+	defer pool.Put(dm)
 
 	records, err := dm.Scan(context.Background())
 	if err != nil {
-		if !provider.reconnecting {
-			go provider.Reconnect()
-		}
+		provider.Reconnect()
 
 		provider.logger.Error("An error occurred while trying to list keys in Olric: %s\n", err)
 
@@ -239,8 +314,21 @@ func (provider *Olric) MapKeys(prefix string) map[string]string {
 
 // GetMultiLevel tries to load the key and check if one of linked keys is a fresh/stale candidate.
 func (provider *Olric) GetMultiLevel(key string, req *http.Request, validator *core.Revalidator) (fresh *http.Response, stale *http.Response) {
-	dm := provider.dm.Get().(olric.DMap)
-	defer provider.dm.Put(dm)
+	if provider.supervisor.Reconnecting() {
+		provider.logger.Error("Impossible to get the olric mapping while reconnecting.")
+
+		return fresh, stale
+	}
+
+	dm, pool := provider.dmap()
+	if dm == nil {
+		provider.logger.Error("Impossible to get an olric DMap without client.")
+
+		return fresh, stale
+	}
+
+	defer pool.Put(dm)
+
 	res, e := dm.Get(context.Background(), key)
 
 	if e != nil {
@@ -255,10 +343,22 @@ func (provider *Olric) GetMultiLevel(key string, req *http.Request, validator *c
 
 // SetMultiLevel tries to store the key with the given value and update the mapping key to store metadata.
 func (provider *Olric) SetMultiLevel(baseKey, variedKey string, value []byte, variedHeaders http.Header, etag string, duration time.Duration, realKey string) error {
+	if provider.supervisor.Reconnecting() {
+		provider.logger.Error("Impossible to set the olric value while reconnecting.")
+
+		return errors.New("reconnecting error")
+	}
+
 	now := time.Now()
 
-	dmap := provider.dm.Get().(olric.DMap)
-	defer provider.dm.Put(dmap)
+	dmap, pool := provider.dmap()
+	if dmap == nil {
+		provider.logger.Error("Impossible to get an olric DMap without client.")
+
+		return errors.New("no olric client")
+	}
+
+	defer pool.Put(dmap)
 
 	compressed := new(bytes.Buffer)
 
@@ -300,19 +400,25 @@ func (provider *Olric) SetMultiLevel(baseKey, variedKey string, value []byte, va
 
 // Get method returns the populated response if exists, empty response then.
 func (provider *Olric) Get(key string) []byte {
-	if provider.reconnecting {
+	if provider.supervisor.Reconnecting() {
 		provider.logger.Error("Impossible to get the olric key while reconnecting.")
 
 		return []byte{}
 	}
 
-	dm := provider.dm.Get().(olric.DMap)
-	defer provider.dm.Put(dm)
+	dm, pool := provider.dmap()
+	if dm == nil {
+		provider.logger.Error("Impossible to get an olric DMap without client.")
+
+		return []byte{}
+	}
+
+	defer pool.Put(dm)
 
 	res, err := dm.Get(context.Background(), key)
 	if err != nil {
-		if !errors.Is(err, olric.ErrKeyNotFound) && !errors.Is(err, olric.ErrKeyTooLarge) && !provider.reconnecting {
-			go provider.Reconnect()
+		if !errors.Is(err, olric.ErrKeyNotFound) && !errors.Is(err, olric.ErrKeyTooLarge) {
+			provider.Reconnect()
 		}
 
 		return []byte{}
@@ -325,20 +431,24 @@ func (provider *Olric) Get(key string) []byte {
 
 // Set method will store the response in Olric provider.
 func (provider *Olric) Set(key string, value []byte, duration time.Duration) error {
-	if provider.reconnecting {
+	if provider.supervisor.Reconnecting() {
 		provider.logger.Error("Impossible to set the olric value while reconnecting.")
 
 		return errors.New("reconnecting error")
 	}
 
-	dm := provider.dm.Get().(olric.DMap)
-	defer provider.dm.Put(dm)
+	dm, pool := provider.dmap()
+	if dm == nil {
+		provider.logger.Error("Impossible to get an olric DMap without client.")
+
+		return errors.New("no olric client")
+	}
+
+	defer pool.Put(dm)
 
 	err := dm.Put(context.Background(), key, value, olric.EX(duration))
 	if err != nil {
-		if !provider.reconnecting {
-			go provider.Reconnect()
-		}
+		provider.Reconnect()
 
 		provider.logger.Errorf("Impossible to set value into Olric, %v", err)
 
@@ -350,14 +460,20 @@ func (provider *Olric) Set(key string, value []byte, duration time.Duration) err
 
 // Delete method will delete the response in Olric provider if exists corresponding to key param.
 func (provider *Olric) Delete(key string) {
-	if provider.reconnecting {
+	if provider.supervisor.Reconnecting() {
 		provider.logger.Error("Impossible to delete the olric key while reconnecting.")
 
 		return
 	}
 
-	dm := provider.dm.Get().(olric.DMap)
-	defer provider.dm.Put(dm)
+	dm, pool := provider.dmap()
+	if dm == nil {
+		provider.logger.Error("Impossible to get an olric DMap without client.")
+
+		return
+	}
+
+	defer pool.Put(dm)
 
 	_, err := dm.Delete(context.Background(), key)
 	if err != nil {
@@ -367,20 +483,30 @@ func (provider *Olric) Delete(key string) {
 
 // DeleteMany method will delete the responses in Olric provider if exists corresponding to the regex key param.
 func (provider *Olric) DeleteMany(key string) {
-	if provider.reconnecting {
+	if provider.supervisor.Reconnecting() {
 		provider.logger.Error("Impossible to delete the olric keys while reconnecting.")
 
 		return
 	}
 
-	dmap := provider.dm.Get().(olric.DMap)
-	defer provider.dm.Put(dmap)
+	matcher, err := core.ParseMatcher(key)
+	if err != nil {
+		return
+	}
+
+	dmap, pool := provider.dmap()
+	if dmap == nil {
+		provider.logger.Error("Impossible to get an olric DMap without client.")
+
+		return
+	}
+
+	defer pool.Put(dmap)
 
-	records, err := dmap.Scan(context.Background(), olric.Match(key))
+	// Olric filters the keys with a Go regexp on its members.
+	records, err := dmap.Scan(context.Background(), olric.Match(matcher.Regexp().String()))
 	if err != nil {
-		if !provider.reconnecting {
-			go provider.Reconnect()
-		}
+		provider.Reconnect()
 
 		provider.logger.Error("An error occurred while trying to list keys in Olric: %s\n", err)
 
@@ -399,30 +525,27 @@ func (provider *Olric) DeleteMany(key string) {
 
 // Init method will initialize Olric provider if needed.
 func (provider *Olric) Init() error {
-	provider.dm = &sync.Pool{
-		New: func() interface{} {
-			dmap, _ := provider.Client.NewDMap("souin-map")
-
-			return dmap
-		},
-	}
-
 	return nil
 }
 
 // Reset method will reset or close provider.
 func (provider *Olric) Reset() error {
-	return provider.Client.Close(context.Background())
+	provider.supervisor.Close()
+
+	if provider.Client() == nil {
+		return nil
+	}
+
+	return provider.Client().Close(context.Background())
 }
 
+// Reconnect replaces the client in the background, the concurrent calls share
+// the same reconnection.
 func (provider *Olric) Reconnect() {
-	provider.reconnecting = true
+	provider.supervisor.Reconnect()
+}
 
-	if c, err := olric.NewClusterClient(provider.addresses, olric.WithConfig(&provider.configuration)); err == nil && c != nil {
-		provider.Client = c
-		provider.reconnecting = false
-	} else {
-		time.Sleep(10 * time.Second)
-		provider.Reconnect()
-	}
+// OnStateChange registers a callback called on each connection state transition.
+func (provider *Olric) OnStateChange(listener func(core.ConnectionState)) {
+	provider.supervisor.OnStateChange(listener)
 }
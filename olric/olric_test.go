@@ -1,6 +1,8 @@
 package olric_test
 
 import (
+	"net/http"
+	"net/http/httptest"
 	"testing"
 	"time"
 
@@ -260,3 +262,22 @@ func TestEmbeddedOlric_Init(t *testing.T) {
 		t.Error("Impossible to init Olric provider")
 	}
 }
+
+func TestOlric_WithoutClient(t *testing.T) {
+	client, err := olric.Factory(core.CacheProvider{URL: "localhost:1"}, zap.NewNop().Sugar(), 0)
+	if err != nil {
+		t.Fatal("The provider should be created while the cluster is unreachable", err)
+	}
+
+	defer func() {
+		_ = client.Reset()
+	}()
+
+	if err = client.SetMultiLevel("Unreachable", "Unreachable", []byte(baseValue), http.Header{}, "", time.Second, "Unreachable"); err == nil {
+		t.Error("The value can't be stored without client")
+	}
+
+	if fresh, _ := client.GetMultiLevel("Unreachable", httptest.NewRequest(http.MethodGet, "/", nil), &core.Revalidator{}); fresh != nil {
+		t.Error("The value can't be loaded without client")
+	}
+}
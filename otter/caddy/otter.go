@@ -1,6 +1,7 @@
 package caddy
 
 import (
+	"io"
 	"net/http"
 
 	caddy "github.com/caddyserver/caddy/v2"
@@ -15,6 +16,8 @@ const moduleName = "otter"
 type Otter struct {
 	// Keep the handler configuration.
 	core.Configuration
+
+	storer core.Storer
 }
 
 //nolint:gochecknoinits
@@ -39,11 +42,21 @@ func (b *Otter) Provision(ctx caddy.Context) error {
 		return err
 	}
 
+	b.storer = storer
 	core.RegisterStorage(storer)
 
 	return nil
 }
 
+// Cleanup releases the otter instance when the module is unloaded.
+func (b *Otter) Cleanup() error {
+	if closer, ok := b.storer.(io.Closer); ok {
+		return closer.Close()
+	}
+
+	return nil
+}
+
 func (b *Otter) ServeHTTP(rw http.ResponseWriter, rq *http.Request, next caddyhttp.Handler) error {
 	return next.ServeHTTP(rw, rq)
 }
@@ -51,5 +64,6 @@ func (b *Otter) ServeHTTP(rw http.ResponseWriter, rq *http.Request, next caddyht
 // Interface guards.
 var (
 	_ caddy.Provisioner           = (*Otter)(nil)
+	_ caddy.CleanerUpper          = (*Otter)(nil)
 	_ caddyhttp.MiddlewareHandler = (*Otter)(nil)
 )
@@ -6,6 +6,7 @@ replace github.com/darkweak/storages/core => ../core
 
 require (
 	github.com/darkweak/storages/core v0.0.15
+	github.com/dustin/go-humanize v1.0.1
 	github.com/maypok86/otter v1.2.4
 	github.com/pierrec/lz4/v4 v4.1.22
 	go.uber.org/zap v1.27.0
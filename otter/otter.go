@@ -2,30 +2,117 @@ package otter
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
+	"math"
 	"net/http"
-	"regexp"
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/darkweak/storages/core"
+	"github.com/dustin/go-humanize"
 	"github.com/maypok86/otter"
 	"github.com/pierrec/lz4/v4"
 )
 
+var _ core.StatsStorer = (*Otter)(nil)
+
+// ErrEntryTooLarge is returned when an entry weighs more than the configured max_entry_size.
+var ErrEntryTooLarge = errors.New("the entry exceeds the otter max_entry_size")
+
 // Otter provider type.
 type Otter struct {
-	cache  *otter.CacheWithVariableTTL[string, []byte]
-	stale  time.Duration
-	logger core.Logger
+	cache        *otter.CacheWithVariableTTL[string, []byte]
+	instance     *instance
+	name         string
+	stale        time.Duration
+	logger       core.Logger
+	maxEntrySize int64
+	closeOnce    sync.Once
+}
+
+// instance is an otter cache shared by every provider configured with the same name.
+type instance struct {
+	name     string
+	cache    otter.CacheWithVariableTTL[string, []byte]
+	weighted bool
+	capacity int
+	refs     int
+	rejected atomic.Int64
+	// Cost of the stored entries in weighted mode, added on set and removed
+	// by the deletion listener.
+	weight atomic.Int64
+
+	snapshotPath string
+	snapshotMu   sync.Mutex
+	stop         chan struct{}
+}
+
+// set stores the entry and accounts its cost in weighted mode.
+func (inst *instance) set(key string, value []byte, duration time.Duration) bool {
+	inserted := inst.cache.Set(key, value, duration)
+	if inserted && inst.weighted {
+		inst.weight.Add(int64(entryCost(key, value)))
+	}
+
+	return inserted
+}
+
+// onDeletion is notified by otter for every replaced, deleted, expired or evicted entry.
+func (inst *instance) onDeletion(key string, value []byte, _ otter.DeletionCause) {
+	if inst.weighted {
+		inst.weight.Add(-int64(entryCost(key, value)))
+	}
 }
 
-var instanceMap = sync.Map{}
+var (
+	instancesMu sync.Mutex
+	instanceMap = map[string]*instance{}
+)
+
+func parseBytes(v interface{}) int64 {
+	switch val := v.(type) {
+	case int:
+		return int64(val)
+	case int64:
+		return val
+	case float64:
+		return int64(val)
+	case string:
+		s, _ := humanize.ParseBytes(val)
+		if s > math.MaxInt64 {
+			return math.MaxInt64
+		}
+
+		//nolint:gosec
+		return int64(s)
+	}
+
+	return 0
+}
+
+// entryCost weighs an entry by its key and value lengths.
+func entryCost(key string, value []byte) uint32 {
+	cost := len(key) + len(value)
+	if cost > math.MaxUint32 {
+		return math.MaxUint32
+	}
+
+	//nolint:gosec
+	return uint32(cost)
+}
 
 // Factory function create new Otter instance.
 func Factory(otterCfg core.CacheProvider, logger core.Logger, stale time.Duration) (core.Storer, error) {
+	var (
+		maxMemory, maxEntrySize int64
+		name, snapshotPath      string
+		snapshotInterval        time.Duration
+	)
+
 	defaultStorageSize := 10_000
 	otterConfiguration := otterCfg.Configuration
 
@@ -42,34 +129,130 @@ func Factory(otterCfg core.CacheProvider, logger core.Logger, stale time.Duratio
 					}
 				}
 			}
+
+			if v, found := oc["max_memory"]; found && v != nil {
+				maxMemory = parseBytes(v)
+			}
+
+			if v, found := oc["max_entry_size"]; found && v != nil {
+				maxEntrySize = parseBytes(v)
+			}
+
+			if v, found := oc["name"]; found && v != nil {
+				name = fmt.Sprint(v)
+			}
+
+			if v, found := oc["snapshot_path"]; found && v != nil {
+				snapshotPath = fmt.Sprint(v)
+			}
+
+			if v, found := oc["snapshot_interval"]; found && v != nil {
+				if val, ok := v.(time.Duration); ok {
+					snapshotInterval = val
+				} else if val, ok := v.(float64); ok {
+					snapshotInterval = time.Duration(val)
+				} else if val, ok := v.(string); ok {
+					snapshotInterval, _ = time.ParseDuration(val)
+				}
+			}
 		}
 	}
 
-	if instance, ok := instanceMap.Load(defaultStorageSize); ok && instance != nil {
-		cache := instance.(otter.CacheWithVariableTTL[string, []byte])
+	// When max_memory is set, the capacity is expressed in bytes and each
+	// entry costs its key and value length instead of 1.
+	capacity := defaultStorageSize
+	weighted := maxMemory > 0
+	costFunc := func(string, []byte) uint32 {
+		return 1
+	}
 
-		return &Otter{
-			cache:  &cache,
-			stale:  stale,
-			logger: logger,
-		}, nil
+	if weighted {
+		capacity = int(min(maxMemory, math.MaxInt))
+		costFunc = entryCost
 	}
 
-	cache, err := otter.MustBuilder[string, []byte](defaultStorageSize).
-		CollectStats().
-		Cost(func(key string, value []byte) uint32 {
-			return 1
-		}).
-		WithVariableTTL().
-		Build()
-	if err != nil {
-		logger.Error("Impossible to instantiate the Otter DB.", err)
+	// Unnamed providers keep sharing the instance that matches their capacity.
+	instanceName := name
+	if instanceName == "" {
+		instanceName = fmt.Sprintf("unnamed-%d-%t", capacity, weighted)
 	}
 
-	instanceMap.Store(defaultStorageSize, cache)
-	logger.Infof("otter.storage.size %d", defaultStorageSize)
+	instancesMu.Lock()
+	defer instancesMu.Unlock()
+
+	inst, ok := instanceMap[instanceName]
+	if ok {
+		if inst.capacity != capacity || inst.weighted != weighted {
+			logger.Warnf("The otter instance %s is already running with another capacity, the new one is ignored.", instanceName)
+		}
+	} else {
+		inst = &instance{}
+
+		cache, err := otter.MustBuilder[string, []byte](capacity).
+			CollectStats().
+			Cost(costFunc).
+			DeletionListener(inst.onDeletion).
+			WithVariableTTL().
+			Build()
+		if err != nil {
+			logger.Error("Impossible to instantiate the Otter DB.", err)
+
+			return nil, err
+		}
 
-	return &Otter{cache: &cache, logger: logger, stale: stale}, nil
+		inst.name = instanceName
+		inst.cache = cache
+		inst.weighted = weighted
+		inst.capacity = capacity
+		inst.snapshotPath = snapshotPath
+		inst.stop = make(chan struct{})
+		instanceMap[instanceName] = inst
+
+		if snapshotPath != "" {
+			loaded, expired, rejected, err := inst.loadSnapshot()
+			if err != nil {
+				logger.Errorf("Impossible to load the otter snapshot %s: %v", snapshotPath, err)
+			}
+
+			logger.Infof(
+				"Loaded %d entries from the otter snapshot %s, skipped %d expired ones and %d rejected by the cache",
+				loaded, snapshotPath, expired, rejected,
+			)
+
+			if snapshotInterval > 0 {
+				go inst.runSnapshots(snapshotInterval, logger)
+			}
+		}
+
+		if weighted {
+			logger.Infof("otter.storage.max_memory %s", humanize.IBytes(uint64(maxMemory)))
+		} else {
+			logger.Infof("otter.storage.size %d", defaultStorageSize)
+		}
+	}
+
+	inst.refs++
+
+	return &Otter{
+		cache:        &inst.cache,
+		instance:     inst,
+		name:         name,
+		stale:        stale,
+		logger:       logger,
+		maxEntrySize: maxEntrySize,
+	}, nil
+}
+
+// checkEntrySize returns an error if the entry weighs more than the configured max_entry_size.
+func (provider *Otter) checkEntrySize(key string, value []byte) error {
+	size := int64(len(key) + len(value))
+	if provider.maxEntrySize > 0 && size > provider.maxEntrySize {
+		provider.instance.rejected.Add(1)
+
+		return fmt.Errorf("%w: the key %s weighs %d bytes, %d allowed", ErrEntryTooLarge, key, size, provider.maxEntrySize)
+	}
+
+	return nil
 }
 
 // Name returns the storer name.
@@ -79,7 +262,11 @@ func (provider *Otter) Name() string {
 
 // Uuid returns an unique identifier.
 func (provider *Otter) Uuid() string {
-	return fmt.Sprint(provider.stale)
+	if provider.name == "" {
+		return fmt.Sprint(provider.stale)
+	}
+
+	return fmt.Sprintf("%s-%s", provider.name, provider.stale)
 }
 
 // MapKeys method returns a map with the key and value.
@@ -153,7 +340,13 @@ func (provider *Otter) SetMultiLevel(baseKey, variedKey string, value []byte, va
 		return err
 	}
 
-	inserted := provider.cache.Set(variedKey, compressed.Bytes(), duration)
+	if err := provider.checkEntrySize(variedKey, compressed.Bytes()); err != nil {
+		provider.logger.Errorf("Impossible to set value into Otter, %v", err)
+
+		return err
+	}
+
+	inserted := provider.instance.set(variedKey, compressed.Bytes(), duration)
 	if !inserted {
 		provider.logger.Errorf("Impossible to set value into Otter, too large for the cost function")
 
@@ -175,7 +368,7 @@ func (provider *Otter) SetMultiLevel(baseKey, variedKey string, value []byte, va
 		return fmt.Errorf("Impossible to generate the duration: %w", err)
 	}
 
-	inserted = provider.cache.Set(mappingKey, val, negativeNow)
+	inserted = provider.instance.set(mappingKey, val, negativeNow)
 	if !inserted {
 		provider.logger.Errorf("Impossible to set value into Otter, too large for the cost function")
 
@@ -187,7 +380,13 @@ func (provider *Otter) SetMultiLevel(baseKey, variedKey string, value []byte, va
 
 // Set method will store the response in Otter provider.
 func (provider *Otter) Set(key string, value []byte, duration time.Duration) error {
-	inserted := provider.cache.Set(key, value, duration)
+	if err := provider.checkEntrySize(key, value); err != nil {
+		provider.logger.Errorf("Impossible to set value into Otter, %v", err)
+
+		return err
+	}
+
+	inserted := provider.instance.set(key, value, duration)
 	if !inserted {
 		provider.logger.Errorf("Impossible to set value into Otter, too large for the cost function")
 	}
@@ -202,16 +401,35 @@ func (provider *Otter) Delete(key string) {
 
 // DeleteMany method will delete the responses in Otter provider if exists corresponding to the regex key param.
 func (provider *Otter) DeleteMany(key string) {
-	rgKey, e := regexp.Compile(key)
+	matcher, e := core.ParseMatcher(key)
 	if e != nil {
 		return
 	}
 
 	provider.cache.DeleteByFunc(func(k string, value []byte) bool {
-		return rgKey.MatchString(k)
+		return matcher.Match(k)
 	})
 }
 
+// Stats returns a snapshot of the Otter cache statistics.
+func (provider *Otter) Stats() core.Stats {
+	otterStats := provider.cache.Stats()
+
+	size := int64(provider.cache.Size())
+	if provider.instance.weighted {
+		// The deletion listener runs asynchronously, it may briefly lag behind the sets.
+		size = max(provider.instance.weight.Load(), 0)
+	}
+
+	return core.Stats{
+		Hits:         otterStats.Hits(),
+		Misses:       otterStats.Misses(),
+		Evictions:    otterStats.EvictedCount(),
+		RejectedSets: otterStats.RejectedSets() + provider.instance.rejected.Load(),
+		Size:         size,
+	}
+}
+
 // Init method will.
 func (provider *Otter) Init() error {
 	return nil
@@ -220,6 +438,46 @@ func (provider *Otter) Init() error {
 // Reset method will reset or close provider.
 func (provider *Otter) Reset() error {
 	provider.cache.Clear()
+	// Otter doesn't notify the cleared entries.
+	provider.instance.weight.Store(0)
+
+	return nil
+}
+
+// Close releases the provider instance, the underlying cache is snapshotted if configured
+// and closed once no provider uses it anymore.
+func (provider *Otter) Close() error {
+	provider.closeOnce.Do(func() {
+		instancesMu.Lock()
+		defer instancesMu.Unlock()
+
+		provider.instance.refs--
+		if provider.instance.refs > 0 {
+			return
+		}
+
+		if instanceMap[provider.instance.name] == provider.instance {
+			delete(instanceMap, provider.instance.name)
+		}
+
+		// Every provider sharing the closed instance is released.
+		for _, storer := range core.GetRegisteredStorers() {
+			if o, ok := storer.(*Otter); ok && o.instance == provider.instance {
+				core.UnregisterStorage(o)
+			}
+		}
+
+		close(provider.instance.stop)
+
+		if provider.instance.snapshotPath != "" {
+			if err := provider.instance.saveSnapshot(); err != nil {
+				provider.logger.Errorf("Impossible to write the otter snapshot %s: %v", provider.instance.snapshotPath, err)
+			}
+		}
+
+		provider.instance.cache.Close()
+		provider.logger.Debugf("Closed the otter instance %s", provider.instance.name)
+	})
 
 	return nil
 }
@@ -1,12 +1,17 @@
 package otter_test
 
 import (
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
 	"testing"
 	"time"
 
 	"github.com/darkweak/storages/core"
 	"github.com/darkweak/storages/otter"
 	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
 )
 
 const (
@@ -133,3 +138,175 @@ func TestOtter_Init(t *testing.T) {
 		t.Error("Impossible to init Otter provider")
 	}
 }
+
+func TestOtter_MaxEntrySize(t *testing.T) {
+	client, _ := otter.Factory(core.CacheProvider{
+		Configuration: map[string]interface{}{
+			"max_memory":     "1MB",
+			"max_entry_size": "1KB",
+		},
+	}, zap.NewNop().Sugar(), 0)
+
+	if err := client.Set("Small", []byte(baseValue), time.Duration(20)*time.Second); err != nil {
+		t.Errorf("Small entry should be stored, got %v", err)
+	}
+
+	err := client.Set("Large", make([]byte, 2000), time.Duration(20)*time.Second)
+	if !errors.Is(err, otter.ErrEntryTooLarge) {
+		t.Errorf("Large entry should be rejected with ErrEntryTooLarge, got %v", err)
+	}
+
+	if 0 < len(client.Get("Large")) {
+		t.Error("Key Large should not exist")
+	}
+
+	if string(client.Get("Small")) != baseValue {
+		t.Error("Key Small should exist")
+	}
+}
+
+func TestOtter_Stats(t *testing.T) {
+	client, _ := otter.Factory(core.CacheProvider{
+		Configuration: map[string]interface{}{
+			"max_memory":     "2MB",
+			"max_entry_size": "1KB",
+		},
+	}, zap.NewNop().Sugar(), 0)
+
+	_ = client.Set("StatsKey", []byte(baseValue), time.Duration(20)*time.Second)
+	_ = client.Set("StatsLarge", make([]byte, 2000), time.Duration(20)*time.Second)
+	_ = client.Get("StatsKey")
+	_ = client.Get(nonExistentKey)
+
+	stats := client.(core.StatsStorer).Stats()
+	if stats.Hits < 1 {
+		t.Errorf("Hits should be at least 1, got %d", stats.Hits)
+	}
+
+	if stats.Misses < 1 {
+		t.Errorf("Misses should be at least 1, got %d", stats.Misses)
+	}
+
+	if stats.RejectedSets < 1 {
+		t.Errorf("RejectedSets should be at least 1, got %d", stats.RejectedSets)
+	}
+
+	cost := int64(len("StatsKey") + len(baseValue))
+	if stats.Size < cost {
+		t.Errorf("Size should account for the stored entry, got %d", stats.Size)
+	}
+
+	// The weighted size follows the deletions without ranging over the cache.
+	client.Delete("StatsKey")
+
+	deadline := time.Now().Add(time.Second)
+	for client.(core.StatsStorer).Stats().Size != stats.Size-cost && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if size := client.(core.StatsStorer).Stats().Size; size != stats.Size-cost {
+		t.Errorf("Size should drop by %d once the entry is deleted, got %d instead of %d", cost, size, stats.Size)
+	}
+}
+
+func getNamedOtterInstance(name string) (core.Storer, error) {
+	return otter.Factory(core.CacheProvider{
+		Configuration: map[string]interface{}{
+			"name": name,
+		},
+	}, zap.NewNop().Sugar(), 0)
+}
+
+func TestOtter_NamedInstancesAreIsolated(t *testing.T) {
+	first, _ := getNamedOtterInstance("first")
+	second, _ := getNamedOtterInstance("second")
+
+	_ = first.Set(byteKey, []byte(baseValue), time.Duration(20)*time.Second)
+	_ = second.Set(byteKey, []byte(baseValue), time.Duration(20)*time.Second)
+
+	if first.Uuid() == second.Uuid() {
+		t.Error("Named instances should have distinct uuids")
+	}
+
+	_ = first.Reset()
+
+	if 0 < len(first.Get(byteKey)) {
+		t.Errorf("Key %s should not exist in the first instance", byteKey)
+	}
+
+	if string(second.Get(byteKey)) != baseValue {
+		t.Errorf("Key %s should still exist in the second instance", byteKey)
+	}
+}
+
+func TestOtter_Close(t *testing.T) {
+	first, _ := getNamedOtterInstance("closable")
+	second, _ := getNamedOtterInstance("closable")
+	name := fmt.Sprintf("%s-%s", second.Name(), second.Uuid())
+
+	core.RegisterStorage(first)
+	core.RegisterStorage(second)
+
+	_ = first.Set(byteKey, []byte(baseValue), time.Duration(20)*time.Second)
+	_ = first.(io.Closer).Close()
+	_ = first.(io.Closer).Close()
+
+	if string(second.Get(byteKey)) != baseValue {
+		t.Errorf("Key %s should exist while the instance is still used", byteKey)
+	}
+
+	if core.GetRegisteredStorer(name) == nil {
+		t.Error("The instance still used should stay registered")
+	}
+
+	_ = second.(io.Closer).Close()
+
+	if core.GetRegisteredStorer(name) != nil {
+		t.Error("The released instance should be unregistered")
+	}
+
+	third, _ := getNamedOtterInstance("closable")
+	if 0 < len(third.Get(byteKey)) {
+		t.Errorf("Key %s should not exist in a released instance", byteKey)
+	}
+}
+
+func TestOtter_SnapshotReload(t *testing.T) {
+	snapshotPath := filepath.Join(t.TempDir(), "otter.snapshot")
+	configuration := core.CacheProvider{
+		Configuration: map[string]interface{}{
+			"name":          "snapshot",
+			"snapshot_path": snapshotPath,
+		},
+	}
+
+	client, _ := otter.Factory(configuration, zap.NewNop().Sugar(), 0)
+	_ = client.Set("Persisted", []byte(baseValue), time.Duration(20)*time.Second)
+	_ = client.Set("Expiring", []byte(baseValue), time.Second)
+	_ = client.Set("Heavy", make([]byte, 2<<20), time.Duration(20)*time.Second)
+	_ = client.(io.Closer).Close()
+
+	time.Sleep(2 * time.Second)
+
+	observed, logs := observer.New(zap.InfoLevel)
+
+	// Heavy weighs more than the whole reloaded cache.
+	configuration.Configuration.(map[string]interface{})["max_memory"] = "1MB"
+	client, _ = otter.Factory(configuration, zap.New(observed).Sugar(), 0)
+	defer func() {
+		_ = client.(io.Closer).Close()
+	}()
+
+	if string(client.Get("Persisted")) != baseValue {
+		t.Error("Key Persisted should have been reloaded from the snapshot")
+	}
+
+	// Otter hides the expired entries on Get, only the load counts tell the expired one was skipped.
+	expected := fmt.Sprintf(
+		"Loaded 1 entries from the otter snapshot %s, skipped 1 expired ones and 1 rejected by the cache",
+		snapshotPath,
+	)
+	if logs.FilterMessage(expected).Len() != 1 {
+		t.Errorf("The snapshot should load Persisted, skip Expiring and count Heavy as rejected, got %v", logs.All())
+	}
+}
@@ -0,0 +1,163 @@
+package otter
+
+import (
+	"bufio"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/darkweak/storages/core"
+)
+
+const snapshotVersion = 1
+
+type snapshotHeader struct {
+	Version   int
+	CreatedAt int64
+}
+
+type snapshotEntry struct {
+	Key   string
+	Value []byte
+	// Unix time in seconds after which the entry is expired.
+	ExpiresAt int64
+}
+
+// saveSnapshot writes every live entry of the instance to its snapshot file.
+// The file is written next to the target and renamed once complete, so a crash
+// never leaves a truncated snapshot behind.
+func (inst *instance) saveSnapshot() error {
+	inst.snapshotMu.Lock()
+	defer inst.snapshotMu.Unlock()
+
+	tmp, err := os.CreateTemp(filepath.Dir(inst.snapshotPath), filepath.Base(inst.snapshotPath)+".tmp-*")
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		_ = tmp.Close()
+		_ = os.Remove(tmp.Name())
+	}()
+
+	writer := bufio.NewWriter(tmp)
+	encoder := gob.NewEncoder(writer)
+
+	if err = encoder.Encode(snapshotHeader{Version: snapshotVersion, CreatedAt: time.Now().Unix()}); err != nil {
+		return err
+	}
+
+	extension := inst.cache.Extension()
+
+	inst.cache.Range(func(key string, _ []byte) bool {
+		entry, found := extension.GetEntryQuietly(key)
+		if !found || entry.HasExpired() {
+			return true
+		}
+
+		err = encoder.Encode(snapshotEntry{Key: key, Value: entry.Value(), ExpiresAt: entry.Expiration()})
+
+		return err == nil
+	})
+
+	if err != nil {
+		return err
+	}
+
+	if err = writer.Flush(); err != nil {
+		return err
+	}
+
+	if err = tmp.Sync(); err != nil {
+		return err
+	}
+
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), inst.snapshotPath)
+}
+
+// loadSnapshot fills the instance with the entries of its snapshot file, skipping the expired ones.
+// The entries refused by the cache (e.g. heavier than its capacity) are counted apart.
+func (inst *instance) loadSnapshot() (loaded int, expired int, rejected int, err error) {
+	file, err := os.Open(inst.snapshotPath)
+	if errors.Is(err, fs.ErrNotExist) {
+		return 0, 0, 0, nil
+	}
+
+	if err != nil {
+		return 0, 0, 0, err
+	}
+
+	defer func() {
+		_ = file.Close()
+	}()
+
+	decoder := gob.NewDecoder(bufio.NewReader(file))
+
+	var header snapshotHeader
+	if err = decoder.Decode(&header); err != nil {
+		return 0, 0, 0, err
+	}
+
+	if header.Version != snapshotVersion {
+		return 0, 0, 0, fmt.Errorf("unsupported otter snapshot version %d", header.Version)
+	}
+
+	now := time.Now().Unix()
+
+	for {
+		var entry snapshotEntry
+		if err = decoder.Decode(&entry); err != nil {
+			if errors.Is(err, io.EOF) {
+				return loaded, expired, rejected, nil
+			}
+
+			return loaded, expired, rejected, err
+		}
+
+		if entry.ExpiresAt <= now {
+			expired++
+
+			continue
+		}
+
+		if inst.set(entry.Key, entry.Value, time.Duration(entry.ExpiresAt-now)*time.Second) {
+			loaded++
+		} else {
+			rejected++
+		}
+	}
+}
+
+func (inst *instance) runSnapshots(interval time.Duration, logger core.Logger) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := inst.saveSnapshot(); err != nil {
+				logger.Errorf("Impossible to write the otter snapshot %s: %v", inst.snapshotPath, err)
+			}
+		case <-inst.stop:
+			return
+		}
+	}
+}
+
+// Snapshot writes the provider entries to the configured snapshot_path.
+func (provider *Otter) Snapshot() error {
+	if provider.instance.snapshotPath == "" {
+		return errors.New("no snapshot_path configured for this otter instance")
+	}
+
+	return provider.instance.saveSnapshot()
+}
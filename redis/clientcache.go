@@ -0,0 +1,148 @@
+package redis
+
+import (
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/darkweak/storages/core"
+	"github.com/dustin/go-humanize"
+	redis "github.com/redis/rueidis"
+)
+
+const (
+	defaultClientSideCacheTTL         = time.Minute
+	defaultClientSideCacheMaxBodySize = 64 << 10
+	// Maximum number of body sizes remembered to decide which bodies are small.
+	maxTrackedBodySizes = 10000
+)
+
+// clientSideCache serves the mapping keys and the small bodies from the rueidis
+// server-assisted client-side cache, Redis pushes the invalidations on writes.
+// The size of a body is only known once read or written by this node, so the
+// bodies are read directly from Redis until then.
+type clientSideCache struct {
+	ttl         time.Duration
+	maxBodySize int
+
+	mu    sync.Mutex
+	sizes map[string]int
+}
+
+// parseClientSideCacheConfiguration returns nil when the ClientSideCache is not enabled.
+func parseClientSideCacheConfiguration(configuration interface{}) *clientSideCache {
+	configMap, ok := configuration.(map[string]interface{})
+	if !ok {
+		return nil
+	}
+
+	enabled := false
+
+	if v := configMap["ClientSideCache"]; v != nil {
+		if b, ok := v.(bool); ok {
+			enabled = b
+		} else if s, ok := v.(string); ok {
+			enabled, _ = strconv.ParseBool(s)
+		}
+	}
+
+	if !enabled {
+		return nil
+	}
+
+	csc := &clientSideCache{
+		ttl:         defaultClientSideCacheTTL,
+		maxBodySize: defaultClientSideCacheMaxBodySize,
+		sizes:       make(map[string]int),
+	}
+
+	if v := configMap["ClientSideCacheTTL"]; v != nil {
+		switch val := v.(type) {
+		case time.Duration:
+			csc.ttl = val
+		case float64:
+			csc.ttl = time.Duration(val)
+		case string:
+			if d, err := time.ParseDuration(val); err == nil {
+				csc.ttl = d
+			}
+		}
+	}
+
+	if v := configMap["ClientSideCacheMaxBodySize"]; v != nil {
+		switch val := v.(type) {
+		case int:
+			csc.maxBodySize = val
+		case float64:
+			csc.maxBodySize = int(val)
+		case string:
+			if s, err := humanize.ParseBytes(val); err == nil {
+				//nolint:gosec
+				csc.maxBodySize = int(s)
+			}
+		}
+	}
+
+	if csc.ttl <= 0 {
+		csc.ttl = defaultClientSideCacheTTL
+	}
+
+	return csc
+}
+
+// cacheable returns true for the bodies known to be small.
+func (csc *clientSideCache) cacheable(key string) bool {
+	csc.mu.Lock()
+	defer csc.mu.Unlock()
+
+	size, found := csc.sizes[key]
+
+	return found && size <= csc.maxBodySize
+}
+
+// remember records the size of the value stored under the key.
+func (csc *clientSideCache) remember(key string, size int) {
+	csc.mu.Lock()
+	defer csc.mu.Unlock()
+
+	if _, found := csc.sizes[key]; !found && len(csc.sizes) >= maxTrackedBodySizes {
+		// Drop any size to make room, the map iteration order is random.
+		for k := range csc.sizes {
+			delete(csc.sizes, k)
+
+			break
+		}
+	}
+
+	csc.sizes[key] = size
+}
+
+// get reads the mapping keys and the small bodies through the client-side
+// cache when it's enabled, the other keys directly from Redis. The reads are
+// served by the replicas when sent to them.
+func (provider *Redis) get(key string) ([]byte, error) {
+	csc := provider.clientSideCache
+	if csc == nil {
+		return provider.readClient.Do(provider.ctx, provider.readClient.B().Get().Key(key).Build()).AsBytes()
+	}
+
+	if strings.HasPrefix(key, provider.hashtags+core.MappingKeyPrefix) {
+		return provider.readClient.DoCache(provider.ctx, provider.readClient.B().Get().Key(key).Cache(), csc.ttl).AsBytes()
+	}
+
+	var result redis.RedisResult
+	if csc.cacheable(key) {
+		result = provider.readClient.DoCache(provider.ctx, provider.readClient.B().Get().Key(key).Cache(), csc.ttl)
+	} else {
+		result = provider.readClient.Do(provider.ctx, provider.readClient.B().Get().Key(key).Build())
+	}
+
+	r, err := result.AsBytes()
+	if err == nil {
+		// The body may have been replaced by a bigger one since.
+		csc.remember(key, len(r))
+	}
+
+	return r, err
+}
@@ -6,6 +6,7 @@ replace github.com/darkweak/storages/core => ../core
 
 require (
 	github.com/darkweak/storages/core v0.0.15
+	github.com/dustin/go-humanize v1.0.1
 	github.com/pierrec/lz4/v4 v4.1.22
 	github.com/redis/rueidis v1.0.54
 	go.uber.org/zap v1.27.0
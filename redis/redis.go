@@ -7,6 +7,7 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"strconv"
 	"strings"
 	"time"
 
@@ -15,15 +16,22 @@ import (
 	redis "github.com/redis/rueidis"
 )
 
+var mappingMergeScript = redis.NewLuaScript(core.MappingMergeScript)
+
 // Redis provider type.
 type Redis struct {
-	inClient      redis.Client
-	stale         time.Duration
-	ctx           context.Context
-	logger        core.Logger
-	configuration redis.ClientOption
-	close         func()
-	hashtags      string
+	inClient        redis.Client
+	stale           time.Duration
+	ctx             context.Context
+	logger          core.Logger
+	configuration   redis.ClientOption
+	close           func()
+	hashtags        string
+	clientSideCache *clientSideCache
+	// rueidis uses its cluster client, it rejects the multi-key commands crossing slots.
+	cluster bool
+	// Serves the reads, the inClient unless they are sent to the Sentinel replicas.
+	readClient redis.Client
 }
 
 // Factory function create new Redis instance.
@@ -38,6 +46,22 @@ func Factory(redisConfiguration core.CacheProvider, logger core.Logger, stale ti
 	}
 
 	if redisConfiguration.Configuration != nil {
+		if configMap, ok := redisConfiguration.Configuration.(map[string]interface{}); ok {
+			if _, ok := configMap["SendToReplicas"]; ok {
+				// SendToReplicas is a function in the rueidis options.
+				filtered := make(map[string]interface{}, len(configMap))
+				for k, v := range configMap {
+					if k != "SendToReplicas" {
+						filtered[k] = v
+					}
+				}
+
+				if redisConfig, err = json.Marshal(filtered); err != nil {
+					return nil, err
+				}
+			}
+		}
+
 		if err := json.Unmarshal(redisConfig, &options); err != nil {
 			logger.Infof("Cannot parse your redis configuration: %+v", err)
 		}
@@ -65,22 +89,51 @@ func Factory(redisConfiguration core.CacheProvider, logger core.Logger, stale ti
 		return nil, errors.New("no redis addresses given.")
 	}
 
+	sendToReplicas := parseReplicaConfiguration(redisConfiguration.Configuration, &options)
+
 	cli, err := redis.NewClient(options)
 	if err != nil {
 		return nil, err
 	}
 
+	readCli := cli
+	closeClients := cli.Close
+
+	if sendToReplicas {
+		if readCli, err = newReplicaClient(options); err != nil {
+			cli.Close()
+
+			return nil, err
+		}
+
+		closeClients = func() {
+			readCli.Close()
+			cli.Close()
+		}
+	}
+
 	return &Redis{
-		inClient:      cli,
-		ctx:           context.Background(),
-		stale:         stale,
-		configuration: options,
-		logger:        logger,
-		close:         cli.Close,
-		hashtags:      hashtags,
+		inClient:        cli,
+		readClient:      readCli,
+		ctx:             context.Background(),
+		stale:           stale,
+		configuration:   options,
+		logger:          logger,
+		close:           closeClients,
+		hashtags:        hashtags,
+		clientSideCache: parseClientSideCacheConfiguration(redisConfiguration.Configuration),
+		cluster:         isCluster(cli),
 	}, err
 }
 
+// isCluster returns true if the server runs in cluster mode, rueidis only
+// falls back to its single client when the cluster support is disabled.
+func isCluster(cli redis.Client) bool {
+	info, err := cli.Do(context.Background(), cli.B().Info().Section("cluster").Build()).ToString()
+
+	return err == nil && strings.Contains(info, "cluster_enabled:1")
+}
+
 // Name returns the storer name.
 func (provider *Redis) Name() string {
 	return "REDIS"
@@ -88,7 +141,7 @@ func (provider *Redis) Name() string {
 
 // Uuid returns an unique identifier.
 func (provider *Redis) Uuid() string {
-	return fmt.Sprintf(
+	uuid := fmt.Sprintf(
 		"%s-%s-%d-%s-%s",
 		strings.Join(provider.configuration.InitAddress, ","),
 		provider.configuration.Username,
@@ -96,38 +149,46 @@ func (provider *Redis) Uuid() string {
 		provider.configuration.ClientName,
 		provider.stale,
 	)
+
+	// The same Sentinels may monitor several masters.
+	if provider.configuration.Sentinel.MasterSet != "" {
+		uuid += "-" + provider.configuration.Sentinel.MasterSet
+	}
+
+	return uuid
 }
 
 // ListKeys method returns the list of existing keys.
 func (provider *Redis) ListKeys() []string {
-	var scan redis.ScanEntry
-
-	var err error
-
 	elements := []string{}
+	mappingKeys := []string{}
 
 	provider.logger.Debugf("Call the ListKeys function in redis")
 
-	for more := true; more; more = scan.Cursor != 0 {
-		if scan, err = provider.inClient.Do(context.Background(), provider.inClient.B().Scan().Cursor(scan.Cursor).Match(provider.hashtags+core.MappingKeyPrefix+"*").Build()).AsScanEntry(); err != nil {
-			provider.logger.Errorf("Cannot scan: %v", err)
-		}
+	err := provider.scan(provider.hashtags+core.MappingKeyPrefix+"*", func(key string) {
+		mappingKeys = append(mappingKeys, key)
+	})
+	if err != nil {
+		provider.logger.Errorf("Cannot scan: %v", err)
+	}
 
-		for _, element := range scan.Elements {
-			value := provider.Get(element)
+	mappings, err := provider.mget(mappingKeys)
+	if err != nil {
+		provider.logger.Errorf("Cannot read the mappings: %v", err)
+	}
+
+	for _, value := range mappings {
+		mapping, err := core.DecodeMapping(value)
+		if err != nil {
+			continue
+		}
 
-			mapping, err := core.DecodeMapping(value)
-			if err != nil {
+		for _, v := range mapping.GetMapping() {
+			if v.GetFreshTime().AsTime().Before(time.Now()) && v.GetStaleTime().AsTime().Before(time.Now()) {
 				continue
 			}
 
-			for _, v := range mapping.GetMapping() {
-				if v.GetFreshTime().AsTime().Before(time.Now()) && v.GetStaleTime().AsTime().Before(time.Now()) {
-					continue
-				}
-
-				elements = append(elements, v.GetRealKey())
-			}
+			elements = append(elements, v.GetRealKey())
 		}
 	}
 
@@ -136,26 +197,25 @@ func (provider *Redis) ListKeys() []string {
 
 // MapKeys method returns the list of existing keys.
 func (provider *Redis) MapKeys(prefix string) map[string]string {
-	var scan redis.ScanEntry
-
-	var err error
-
 	kvStore := map[string]string{}
 	elements := []string{}
 
 	provider.logger.Debugf("Call the MapKeys in redis with the prefix %s", prefix)
 
-	for more := true; more; more = scan.Cursor != 0 {
-		if scan, err = provider.inClient.Do(context.Background(), provider.inClient.B().Scan().Cursor(scan.Cursor).Match(prefix+"*").Build()).AsScanEntry(); err != nil {
-			provider.logger.Errorf("Cannot scan: %v", err)
-		}
+	if err := provider.scan(prefix+"*", func(key string) {
+		elements = append(elements, key)
+	}); err != nil {
+		provider.logger.Errorf("Cannot scan: %v", err)
+	}
 
-		elements = append(elements, scan.Elements...)
+	values, err := provider.mget(elements)
+	if err != nil {
+		provider.logger.Errorf("Cannot read the keys: %v", err)
 	}
 
-	for _, key := range elements {
+	for key, value := range values {
 		k, _ := strings.CutPrefix(key, prefix)
-		kvStore[k] = string(provider.Get(key))
+		kvStore[k] = string(value)
 	}
 
 	return kvStore
@@ -163,7 +223,7 @@ func (provider *Redis) MapKeys(prefix string) map[string]string {
 
 // GetMultiLevel tries to load the key and check if one of linked keys is a fresh/stale candidate.
 func (provider *Redis) GetMultiLevel(key string, req *http.Request, validator *core.Revalidator) (fresh *http.Response, stale *http.Response) {
-	b, e := provider.inClient.Do(provider.ctx, provider.inClient.B().Get().Key(provider.hashtags+core.MappingKeyPrefix+key).Build()).AsBytes()
+	b, e := provider.get(provider.hashtags + core.MappingKeyPrefix + key)
 	if e != nil {
 		return
 	}
@@ -184,25 +244,37 @@ func (provider *Redis) SetMultiLevel(baseKey, variedKey string, value []byte, va
 		return err
 	}
 
-	if err := provider.inClient.Do(provider.ctx, provider.inClient.B().Set().Key(provider.hashtags+variedKey).Value(compressed.String()).Ex(duration+provider.stale).Build()).Error(); err != nil {
-		provider.logger.Errorf("Impossible to set value into Redis, %v", err)
+	bodyKey := provider.hashtags + variedKey
+	if provider.clientSideCache != nil {
+		provider.clientSideCache.remember(bodyKey, compressed.Len())
+	}
 
+	entry, err := core.MappingUpdater(bodyKey, nil, provider.logger, now, now.Add(duration), now.Add(duration+provider.stale), variedHeaders, etag, realKey)
+	if err != nil {
 		return err
 	}
 
 	mappingKey := provider.hashtags + core.MappingKeyPrefix + baseKey
-
-	v, err := provider.inClient.Do(provider.ctx, provider.inClient.B().Get().Key(mappingKey).Build()).AsBytes()
-	if err != nil && !errors.Is(err, redis.Nil) {
-		return err
+	args := []string{
+		string(entry),
+		bodyKey,
+		strconv.FormatInt(now.UnixMilli(), 10),
+		compressed.String(),
+		strconv.FormatInt((duration + provider.stale).Milliseconds(), 10),
 	}
 
-	val, err := core.MappingUpdater(provider.hashtags+variedKey, v, provider.logger, now, now.Add(duration), now.Add(duration+provider.stale), variedHeaders, etag, realKey)
-	if err != nil {
-		return err
+	// rueidis rejects the multi-key commands crossing cluster slots before
+	// sending them, without HashTag only the mapping merge stays atomic.
+	if !provider.cluster || core.ClusterSlot(mappingKey) == core.ClusterSlot(bodyKey) {
+		err = mappingMergeScript.Exec(provider.ctx, provider.inClient, []string{mappingKey, bodyKey}, args).Error()
+	} else {
+		err = provider.inClient.Do(provider.ctx, provider.inClient.B().Set().Key(bodyKey).Value(compressed.String()).Px(duration+provider.stale).Build()).Error()
+		if err == nil {
+			err = mappingMergeScript.Exec(provider.ctx, provider.inClient, []string{mappingKey}, args).Error()
+		}
 	}
 
-	if err = provider.inClient.Do(provider.ctx, provider.inClient.B().Set().Key(mappingKey).Value(string(val)).Build()).Error(); err != nil {
+	if err != nil {
 		provider.logger.Errorf("Impossible to set value into Redis, %v", err)
 	}
 
@@ -211,7 +283,7 @@ func (provider *Redis) SetMultiLevel(baseKey, variedKey string, value []byte, va
 
 // Get method returns the populated response if exists, empty response then.
 func (provider *Redis) Get(key string) []byte {
-	r, e := provider.inClient.Do(provider.ctx, provider.inClient.B().Get().Key(key).Build()).AsBytes()
+	r, e := provider.get(key)
 	if e != nil && !errors.Is(e, redis.Nil) {
 		return nil
 	}
@@ -243,23 +315,31 @@ func (provider *Redis) Delete(key string) {
 
 // DeleteMany method will delete the responses in Redis provider if exists corresponding to the regex key param.
 func (provider *Redis) DeleteMany(key string) {
-	var scan redis.ScanEntry
+	matcher, err := core.ParseMatcher(key)
+	if err != nil {
+		provider.logger.Errorf("The key %s is not a valid pattern: %v", key, err)
 
-	var err error
+		return
+	}
 
 	elements := []string{}
 
 	provider.logger.Debugf("Call the DeleteMany function in redis")
 
-	for more := true; more; more = scan.Cursor != 0 {
-		if scan, err = provider.inClient.Do(context.Background(), provider.inClient.B().Scan().Cursor(scan.Cursor).Match(key).Build()).AsScanEntry(); err != nil {
-			provider.logger.Errorf("Cannot scan: %v", err)
+	// SCAN MATCH only narrows the keys down to the matcher literal prefix.
+	if err := provider.scan(matcher.Glob(), func(key string) {
+		if matcher.Match(key) {
+			elements = append(elements, key)
 		}
+	}); err != nil {
+		provider.logger.Errorf("Cannot scan: %v", err)
 
-		elements = append(elements, scan.Elements...)
+		return
 	}
 
-	_ = provider.inClient.Do(provider.ctx, provider.inClient.B().Del().Key(elements...).Build())
+	if err := provider.del(elements); err != nil {
+		provider.logger.Errorf("Impossible to delete the keys matching %s in Redis, %v", key, err)
+	}
 }
 
 // Init method will.
@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"github.com/darkweak/storages/core"
+	"github.com/darkweak/storages/core/storertest"
 	"github.com/darkweak/storages/redis"
 	"go.uber.org/zap"
 )
@@ -138,15 +139,116 @@ func TestRedis_DeleteMany(t *testing.T) {
 		t.Error("The map should contain 12 elements")
 	}
 
-	client.DeleteMany("MAP_KEYS_PREFIX_*")
+	client.DeleteMany("glob:MAP_KEYS_PREFIX_*")
 
 	if len(client.MapKeys("")) != 2 {
 		t.Error("The map should contain 2 element")
 	}
 
-	client.DeleteMany("*")
+	client.DeleteMany(".*")
 
 	if len(client.MapKeys("")) != 0 {
 		t.Error("The map should be empty")
 	}
 }
+
+func TestRedis_ClientSideCache(t *testing.T) {
+	writer, _ := getRedisInstance()
+	reader, _ := redis.Factory(core.CacheProvider{
+		Configuration: map[string]interface{}{
+			"InitAddress":     []string{"localhost:6379"},
+			"ClientSideCache": true,
+		},
+	}, zap.NewNop().Sugar(), 0)
+
+	defer writer.DeleteMany(".*")
+
+	mappingKey := core.MappingKeyPrefix + "ClientSideCached"
+
+	_ = writer.Set(mappingKey, []byte(baseValue), 20*time.Second)
+
+	// Read twice, the second one is served locally.
+	for range 2 {
+		if string(reader.Get(mappingKey)) != baseValue {
+			t.Errorf("Key %s should exist", mappingKey)
+		}
+	}
+
+	_ = writer.Set(mappingKey, []byte("A"), 20*time.Second)
+	time.Sleep(100 * time.Millisecond)
+
+	if string(reader.Get(mappingKey)) != "A" {
+		t.Errorf("The client-side cached key %s should be invalidated by Redis", mappingKey)
+	}
+
+	writer.Delete(mappingKey)
+	time.Sleep(100 * time.Millisecond)
+
+	if 0 < len(reader.Get(mappingKey)) {
+		t.Errorf("The client-side cached key %s should be deleted", mappingKey)
+	}
+}
+
+func TestRedis_SetMultiLevel(t *testing.T) {
+	client, _ := getRedisInstance()
+
+	storertest.SetMultiLevel(t, client)
+}
+
+func TestRedis_Cluster(t *testing.T) {
+	client, err := redis.Factory(core.CacheProvider{Configuration: map[string]interface{}{
+		"InitAddress": []string{"localhost:7000", "localhost:7001", "localhost:7002"},
+	}}, zap.NewNop().Sugar(), 0)
+	if err != nil {
+		storertest.Unreachable(t, "redis cluster", err)
+	}
+
+	storertest.Cluster(t, client)
+}
+
+func TestRedis_SentinelReplicaReads(t *testing.T) {
+	client, err := redis.Factory(core.CacheProvider{Configuration: map[string]interface{}{
+		"InitAddress":    []string{"localhost:26379"},
+		"MasterName":     "mymaster",
+		"SendToReplicas": true,
+	}}, zap.NewNop().Sugar(), 0)
+	if err != nil {
+		storertest.Unreachable(t, "redis sentinel", err)
+	}
+
+	defer client.Delete("SentinelKey")
+
+	if err = client.Set("SentinelKey", []byte(baseValue), 20*time.Second); err != nil {
+		t.Errorf("The key SentinelKey should be written on the master: %v", err)
+	}
+
+	if string(storertest.EventuallyGet(client, "SentinelKey")) != baseValue {
+		t.Error("The key SentinelKey should be readable from the replica")
+	}
+}
+
+func TestRedis_ClusterReplicaReads(t *testing.T) {
+	client, err := redis.Factory(core.CacheProvider{Configuration: map[string]interface{}{
+		"InitAddress":    []string{"localhost:7000", "localhost:7001", "localhost:7002"},
+		"SendToReplicas": true,
+	}}, zap.NewNop().Sugar(), 0)
+	if err != nil {
+		storertest.Unreachable(t, "redis cluster", err)
+	}
+
+	defer client.Delete("ReplicaKey")
+
+	if err = client.Set("ReplicaKey", []byte(baseValue), 20*time.Second); err != nil {
+		t.Errorf("The key ReplicaKey should be written on the master: %v", err)
+	}
+
+	if string(storertest.EventuallyGet(client, "ReplicaKey")) != baseValue {
+		t.Error("The key ReplicaKey should be readable from the replicas")
+	}
+}
+
+func TestRedis_MapKeysBatches(t *testing.T) {
+	client, _ := getRedisInstance()
+
+	storertest.MapKeysBatches(t, client)
+}
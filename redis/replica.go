@@ -0,0 +1,69 @@
+package redis
+
+import (
+	"strconv"
+
+	redis "github.com/redis/rueidis"
+)
+
+// parseReplicaConfiguration applies the Sentinel and replica reads settings.
+// The Sentinels are set either with the rueidis Sentinel object or with the
+// go-redis MasterName, SentinelUsername and SentinelPassword keys, InitAddress
+// then lists the Sentinels.
+// SendToReplicas sends the read-only commands to the replicas, it returns
+// true when a dedicated replica client is required to do so.
+func parseReplicaConfiguration(configuration interface{}, options *redis.ClientOption) bool {
+	configMap, ok := configuration.(map[string]interface{})
+	if !ok {
+		return false
+	}
+
+	if v, ok := configMap["MasterName"].(string); ok && options.Sentinel.MasterSet == "" {
+		options.Sentinel.MasterSet = v
+	}
+
+	if v, ok := configMap["SentinelUsername"].(string); ok && options.Sentinel.Username == "" {
+		options.Sentinel.Username = v
+	}
+
+	if v, ok := configMap["SentinelPassword"].(string); ok && options.Sentinel.Password == "" {
+		options.Sentinel.Password = v
+	}
+
+	if options.Sentinel.MasterSet != "" && options.Sentinel.Dialer.Timeout == 0 {
+		options.Sentinel.Dialer.Timeout = options.Dialer.Timeout
+	}
+
+	sendToReplicas := false
+
+	if v := configMap["SendToReplicas"]; v != nil {
+		if b, ok := v.(bool); ok {
+			sendToReplicas = b
+		} else if s, ok := v.(string); ok {
+			sendToReplicas, _ = strconv.ParseBool(s)
+		}
+	}
+
+	if !sendToReplicas || options.ReplicaOnly {
+		return false
+	}
+
+	// The sentinel client connects either to the master or to a replica.
+	if options.Sentinel.MasterSet != "" {
+		return true
+	}
+
+	options.SendToReplicas = func(cmd redis.Completed) bool {
+		return cmd.IsReadOnly()
+	}
+
+	return false
+}
+
+// newReplicaClient creates the client connected to a replica of the master
+// monitored by the Sentinels, it serves the reads.
+func newReplicaClient(options redis.ClientOption) (redis.Client, error) {
+	options.ReplicaOnly = true
+
+	return redis.NewClient(options)
+}
@@ -0,0 +1,129 @@
+package redis
+
+import (
+	"github.com/darkweak/storages/core"
+	redis "github.com/redis/rueidis"
+)
+
+const (
+	// Number of keys asked per SCAN call.
+	scanCount = 1000
+	// Maximum number of keys sent in a single multi-key command.
+	batchSize = 1000
+	// Maximum number of multi-key commands sent in a single pipeline.
+	pipelineSize = 16
+)
+
+// masters returns the nodes to scan. A cluster client also connects to the
+// replicas which hold the same keys as their master.
+func (provider *Redis) masters() ([]redis.Client, error) {
+	nodes := provider.inClient.Nodes()
+	masters := make([]redis.Client, 0, len(nodes))
+
+	if len(nodes) == 1 {
+		for _, node := range nodes {
+			masters = append(masters, node)
+		}
+
+		return masters, nil
+	}
+
+	for _, node := range nodes {
+		role, err := node.Do(provider.ctx, node.B().Role().Build()).ToArray()
+		if err != nil {
+			return nil, err
+		}
+
+		if len(role) > 0 {
+			if kind, _ := role[0].ToString(); kind == "master" {
+				masters = append(masters, node)
+			}
+		}
+	}
+
+	return masters, nil
+}
+
+// scan calls fn once for every key matching the glob pattern. A SCAN only
+// walks the node it is sent to, so every master is scanned when connected to
+// a cluster, the keys returned by several SCAN calls are skipped.
+func (provider *Redis) scan(match string, fn func(key string)) error {
+	masters, err := provider.masters()
+	if err != nil {
+		return err
+	}
+
+	seen := make(map[string]struct{})
+
+	for _, node := range masters {
+		var entry redis.ScanEntry
+
+		for more := true; more; more = entry.Cursor != 0 {
+			entry, err = node.Do(provider.ctx, node.B().Scan().Cursor(entry.Cursor).Match(match).Count(scanCount).Build()).AsScanEntry()
+			if err != nil {
+				return err
+			}
+
+			for _, key := range entry.Elements {
+				if _, found := seen[key]; !found {
+					seen[key] = struct{}{}
+
+					fn(key)
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// mget returns the values of the existing keys. The keys are grouped by slot
+// and the MGET commands pipelined by chunks of pipelineSize to their nodes.
+func (provider *Redis) mget(keys []string) (map[string][]byte, error) {
+	values := make(map[string][]byte, len(keys))
+	batches := core.GroupBySlot(keys, batchSize)
+
+	for len(batches) > 0 {
+		chunk := batches[:min(len(batches), pipelineSize)]
+		batches = batches[len(chunk):]
+		commands := make(redis.Commands, 0, len(chunk))
+
+		for _, batch := range chunk {
+			commands = append(commands, provider.readClient.B().Mget().Key(batch...).Build())
+		}
+
+		for i, result := range provider.readClient.DoMulti(provider.ctx, commands...) {
+			messages, err := result.ToArray()
+			if err != nil {
+				return values, err
+			}
+
+			for j, message := range messages {
+				// The missing keys are nil.
+				if value, err := message.AsBytes(); err == nil {
+					values[chunk[i][j]] = value
+				}
+			}
+		}
+	}
+
+	return values, nil
+}
+
+// del deletes the keys with one DEL per slot-grouped batch, pipelined to their nodes.
+func (provider *Redis) del(keys []string) error {
+	batches := core.GroupBySlot(keys, batchSize)
+	commands := make(redis.Commands, 0, len(batches))
+
+	for _, batch := range batches {
+		commands = append(commands, provider.inClient.B().Del().Key(batch...).Build())
+	}
+
+	for _, result := range provider.inClient.DoMulti(provider.ctx, commands...) {
+		if err := result.Error(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,259 @@
+package simplefs
+
+import (
+	"container/heap"
+	"container/list"
+	"strings"
+
+	"github.com/darkweak/storages/core"
+	"github.com/jellydator/ttlcache/v3"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	lruPolicy = "lru"
+	lfuPolicy = "lfu"
+)
+
+// policyEntry is a body stored on the disk and tracked by the eviction policy.
+type policyEntry struct {
+	key     string
+	baseKey string
+	path    string
+	size    int64
+	head    []byte
+	item    *ttlcache.Item[string, []byte]
+
+	// LFU bookkeeping.
+	frequency int
+	lastUsed  uint64
+	index     int
+}
+
+// evictionPolicy orders the stored bodies to pick the next one to remove when
+// the directory_size is reached.
+type evictionPolicy interface {
+	// push tracks a new entry, replacing the previous one with the same key.
+	push(entry *policyEntry)
+	// touch records an access to the key.
+	touch(key string)
+	get(key string) *policyEntry
+	remove(key string)
+	// victim returns the next entry to evict, nil if there is none.
+	victim() *policyEntry
+}
+
+func newEvictionPolicy(name string) evictionPolicy {
+	if strings.EqualFold(name, lfuPolicy) {
+		return &lfu{entries: map[string]*policyEntry{}}
+	}
+
+	return &lru{order: list.New(), elements: map[string]*list.Element{}}
+}
+
+// lru evicts the least recently used entry first.
+type lru struct {
+	order    *list.List
+	elements map[string]*list.Element
+}
+
+func (l *lru) push(entry *policyEntry) {
+	l.remove(entry.key)
+	l.elements[entry.key] = l.order.PushFront(entry)
+}
+
+func (l *lru) touch(key string) {
+	if element, ok := l.elements[key]; ok {
+		l.order.MoveToFront(element)
+	}
+}
+
+func (l *lru) get(key string) *policyEntry {
+	if element, ok := l.elements[key]; ok {
+		return element.Value.(*policyEntry)
+	}
+
+	return nil
+}
+
+func (l *lru) remove(key string) {
+	if element, ok := l.elements[key]; ok {
+		l.order.Remove(element)
+		delete(l.elements, key)
+	}
+}
+
+func (l *lru) victim() *policyEntry {
+	if back := l.order.Back(); back != nil {
+		return back.Value.(*policyEntry)
+	}
+
+	return nil
+}
+
+// lfu evicts the least frequently used entry first, the least recently used one on ties.
+type lfu struct {
+	entries map[string]*policyEntry
+	queue   lfuQueue
+	clock   uint64
+}
+
+func (l *lfu) push(entry *policyEntry) {
+	l.remove(entry.key)
+	l.clock++
+	entry.frequency = 1
+	entry.lastUsed = l.clock
+	l.entries[entry.key] = entry
+	heap.Push(&l.queue, entry)
+}
+
+func (l *lfu) touch(key string) {
+	if entry, ok := l.entries[key]; ok {
+		l.clock++
+		entry.frequency++
+		entry.lastUsed = l.clock
+		heap.Fix(&l.queue, entry.index)
+	}
+}
+
+func (l *lfu) get(key string) *policyEntry {
+	return l.entries[key]
+}
+
+func (l *lfu) remove(key string) {
+	if entry, ok := l.entries[key]; ok {
+		heap.Remove(&l.queue, entry.index)
+		delete(l.entries, key)
+	}
+}
+
+func (l *lfu) victim() *policyEntry {
+	if len(l.queue) == 0 {
+		return nil
+	}
+
+	return l.queue[0]
+}
+
+type lfuQueue []*policyEntry
+
+func (q lfuQueue) Len() int {
+	return len(q)
+}
+
+func (q lfuQueue) Less(i, j int) bool {
+	if q[i].frequency == q[j].frequency {
+		return q[i].lastUsed < q[j].lastUsed
+	}
+
+	return q[i].frequency < q[j].frequency
+}
+
+func (q lfuQueue) Swap(i, j int) {
+	q[i], q[j] = q[j], q[i]
+	q[i].index = i
+	q[j].index = j
+}
+
+func (q *lfuQueue) Push(x any) {
+	entry := x.(*policyEntry)
+	entry.index = len(*q)
+	*q = append(*q, entry)
+}
+
+func (q *lfuQueue) Pop() any {
+	old := *q
+	n := len(old)
+	entry := old[n-1]
+	old[n-1] = nil
+	*q = old[:n-1]
+
+	return entry
+}
+
+// track registers the stored body in the eviction policy and the directory size.
+// It must be called with the provider lock held.
+func (provider *Simplefs) track(entry *policyEntry) {
+	if previous := provider.eviction.get(entry.key); previous != nil {
+		provider.actualSize -= previous.size
+	}
+
+	provider.eviction.push(entry)
+	provider.actualSize += entry.size
+	provider.logger.Debugf("Actual size add: %d, new: %d", entry.size, provider.actualSize)
+}
+
+// forget removes the body from the disk, the eviction policy and its mapping.
+// It must be called with the provider lock held.
+func (provider *Simplefs) forget(key string) {
+	entry := provider.eviction.get(key)
+	if entry == nil {
+		return
+	}
+
+	provider.eviction.remove(key)
+	provider.actualSize -= entry.size
+	provider.logger.Debugf("Actual size remove: %d, new: %d", entry.size, provider.actualSize)
+
+	if err := onEvict(entry.path); err != nil {
+		provider.logger.Errorf("impossible to remove the file %s: %#v", entry.key, err)
+	}
+
+	provider.removeFromMapping(entry.baseKey, key)
+}
+
+// removeFromMapping drops the variant from its mapping, and the mapping itself once empty.
+func (provider *Simplefs) removeFromMapping(baseKey, key string) {
+	if baseKey == "" {
+		return
+	}
+
+	mappingKey := core.MappingKeyPrefix + baseKey
+
+	item := provider.cache.Get(mappingKey)
+	if item == nil {
+		return
+	}
+
+	mapping, err := core.DecodeMapping(item.Value())
+	if err != nil {
+		return
+	}
+
+	if _, found := mapping.GetMapping()[key]; !found {
+		return
+	}
+
+	delete(mapping.GetMapping(), key)
+
+	if len(mapping.GetMapping()) == 0 {
+		provider.cache.Delete(mappingKey)
+
+		return
+	}
+
+	val, err := proto.Marshal(mapping)
+	if err != nil {
+		provider.logger.Errorf("Impossible to encode the mapping value for the key %s, %v", mappingKey, err)
+
+		return
+	}
+
+	_ = provider.cache.Set(mappingKey, val, item.TTL())
+}
+
+// recoverEnoughSpaceIfNeeded evicts the entries in the policy order until the
+// new body fits in the directory_size.
+// It must be called with the provider lock held.
+func (provider *Simplefs) recoverEnoughSpaceIfNeeded(size int64) {
+	for provider.directorySize > -1 && provider.actualSize+size > provider.directorySize {
+		entry := provider.eviction.victim()
+		if entry == nil {
+			return
+		}
+
+		provider.logger.Debugf("Evict the key %s to recover %d bytes", entry.key, entry.size)
+		provider.forget(entry.key)
+		provider.cache.Delete(entry.key)
+	}
+}
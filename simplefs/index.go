@@ -0,0 +1,116 @@
+package simplefs
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/darkweak/storages/core"
+	"github.com/jellydator/ttlcache/v3"
+)
+
+func removeEntryFiles(bodyPath string) {
+	_ = os.Remove(bodyPath)
+	_ = os.Remove(metadataPath(bodyPath))
+}
+
+// rebuildIndex restores the entries and their mappings from the metadata stored
+// next to the bodies. The orphaned and expired files are removed, as well as the
+// temporary files left by an interrupted write.
+func (provider *Simplefs) rebuildIndex() {
+	now := time.Now()
+	restored, removed := 0, 0
+
+	_ = filepath.WalkDir(provider.path, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+
+		if d.IsDir() {
+			// Only walk the shard directories, the path may be shared with other files.
+			if path != filepath.Clean(provider.path) && !isShardDir(d.Name()) {
+				return filepath.SkipDir
+			}
+
+			return nil
+		}
+
+		if !provider.isShardedFile(path) {
+			return nil
+		}
+
+		if isTempFile(d.Name()) {
+			provider.logger.Debugf("Remove the partially written file %s", path)
+			_ = os.Remove(path)
+
+			return nil
+		}
+
+		if isMetadataFile(d.Name()) {
+			// Metadata without its body.
+			if _, err := os.Stat(strings.TrimSuffix(path, metadataExtension)); os.IsNotExist(err) {
+				_ = os.Remove(path)
+			}
+
+			return nil
+		}
+
+		metadata, err := readMetadata(path)
+		if err != nil || metadata.Key == "" || metadata.BaseKey == "" || !now.Before(metadata.FreshUntil) {
+			provider.logger.Debugf("Remove the orphaned or expired file %s", path)
+			removeEntryFiles(path)
+
+			removed++
+
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return nil
+		}
+
+		provider.mu.Lock()
+		defer provider.mu.Unlock()
+
+		stored := provider.cache.Set(metadata.Key, []byte(path), metadata.FreshUntil.Sub(now))
+		provider.track(&policyEntry{
+			key:     metadata.Key,
+			baseKey: metadata.BaseKey,
+			path:    path,
+			size:    info.Size(),
+			head:    metadata.Head,
+			item:    stored,
+		})
+
+		mappingKey := core.MappingKeyPrefix + metadata.BaseKey
+
+		var mapping []byte
+		if item := provider.cache.Get(mappingKey); item != nil {
+			mapping = item.Value()
+		}
+
+		val, e := core.MappingUpdater(
+			metadata.Key,
+			mapping,
+			provider.logger,
+			metadata.StoredAt,
+			metadata.FreshUntil,
+			metadata.StaleUntil,
+			metadata.VariedHeaders,
+			metadata.Etag,
+			metadata.RealKey,
+		)
+		if e == nil {
+			_ = provider.cache.Set(mappingKey, val, ttlcache.NoTTL)
+		}
+
+		restored++
+
+		return nil
+	})
+
+	provider.logger.Infof("Restored %d simplefs entries from %s, removed %d orphaned or expired files", restored, provider.path, removed)
+}
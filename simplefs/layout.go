@@ -0,0 +1,267 @@
+package simplefs
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pierrec/lz4/v4"
+)
+
+const metadataExtension = ".meta"
+
+// Separator between the base key and the varied headers in the keys of the
+// responses with a Vary header.
+const varySeparator = "{-VARY-}"
+
+var errNotFlatBody = errors.New("not an lz4 compressed body")
+
+// lz4 frame magic number, every body of the flat layout starts with it.
+var lz4Magic = []byte{0x04, 0x22, 0x4d, 0x18}
+
+// entryMetadata is stored next to each body file to rebuild the index on startup.
+type entryMetadata struct {
+	// Original cache key.
+	Key string `json:"key"`
+	// Key of the mapping the entry belongs to, without the mapping prefix.
+	BaseKey       string      `json:"base_key,omitempty"`
+	RealKey       string      `json:"real_key,omitempty"`
+	Etag          string      `json:"etag,omitempty"`
+	VariedHeaders http.Header `json:"varied_headers,omitempty"`
+	StoredAt      time.Time   `json:"stored_at"`
+	FreshUntil    time.Time   `json:"fresh_until"`
+	StaleUntil    time.Time   `json:"stale_until"`
+	// Status line and headers of the response when the body is stored uncompressed.
+	Head []byte `json:"head,omitempty"`
+}
+
+// filePath returns the sharded location of the key body, e.g. <path>/ab/cd/abcd...
+// Hashing keeps every file name short and spreads the entries across directories.
+func (provider *Simplefs) filePath(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	name := hex.EncodeToString(sum[:])
+
+	return filepath.Join(provider.path, name[:2], name[2:4], name)
+}
+
+// isShardDir returns true if the name can be a shard directory (ab).
+func isShardDir(name string) bool {
+	return len(name) == 2 && isHex(name)
+}
+
+func isHex(name string) bool {
+	_, err := hex.DecodeString(name)
+
+	return err == nil
+}
+
+// isShardedFile returns true if the path is a body, a metadata or a temporary
+// file written by the provider in its shard directory (<path>/ab/cd/abcd...).
+// The directory may be shared, every other file must be left untouched.
+func (provider *Simplefs) isShardedFile(path string) bool {
+	shard := filepath.Dir(path)
+	parent := filepath.Dir(shard)
+
+	if filepath.Dir(parent) != filepath.Clean(provider.path) {
+		return false
+	}
+
+	name := filepath.Base(path)
+	if isTempFile(name) {
+		// <prefix><hash>[.meta]-<random>
+		name = strings.TrimPrefix(name, tempFilePrefix)
+		if i := strings.LastIndexByte(name, '-'); i >= 0 {
+			name = name[:i]
+		}
+	}
+
+	name = strings.TrimSuffix(name, metadataExtension)
+
+	return len(name) == sha256.Size*2 && isHex(name) &&
+		filepath.Base(parent) == name[:2] && filepath.Base(shard) == name[2:4]
+}
+
+func metadataPath(bodyPath string) string {
+	return bodyPath + metadataExtension
+}
+
+func isMetadataFile(name string) bool {
+	return strings.HasSuffix(name, metadataExtension)
+}
+
+func readMetadata(bodyPath string) (*entryMetadata, error) {
+	content, err := os.ReadFile(metadataPath(bodyPath))
+	if err != nil {
+		return nil, err
+	}
+
+	metadata := &entryMetadata{}
+
+	return metadata, json.Unmarshal(content, metadata)
+}
+
+func (provider *Simplefs) writeMetadata(bodyPath string, metadata *entryMetadata) error {
+	content, err := json.Marshal(metadata)
+	if err != nil {
+		return err
+	}
+
+	return provider.writeFile(metadataPath(bodyPath), content)
+}
+
+// readFlatResponse returns the response stored in the flat layout file, the
+// file must be an lz4 compressed HTTP response.
+func readFlatResponse(path string) (*http.Response, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() {
+		_ = file.Close()
+	}()
+
+	magic := make([]byte, len(lz4Magic))
+	if _, err = io.ReadFull(file, magic); err != nil {
+		return nil, err
+	}
+
+	if !bytes.Equal(magic, lz4Magic) {
+		return nil, errNotFlatBody
+	}
+
+	if _, err = file.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+
+	response, err := http.ReadResponse(bufio.NewReader(lz4.NewReader(file)), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	_ = response.Body.Close()
+
+	return response, nil
+}
+
+// freshUntil returns the end of the response freshness from its s-maxage,
+// max-age or Expires header. The flat layout didn't store the TTL.
+func freshUntil(response *http.Response, storedAt time.Time) (time.Time, bool) {
+	if date, err := http.ParseTime(response.Header.Get("Date")); err == nil {
+		storedAt = date
+	}
+
+	directives := map[string]string{}
+
+	for _, directive := range strings.Split(response.Header.Get("Cache-Control"), ",") {
+		name, value, _ := strings.Cut(strings.TrimSpace(directive), "=")
+		directives[strings.ToLower(name)] = strings.Trim(value, `"`)
+	}
+
+	for _, name := range []string{"s-maxage", "max-age"} {
+		if age, err := strconv.Atoi(directives[name]); err == nil {
+			return storedAt.Add(time.Duration(age) * time.Second), true
+		}
+	}
+
+	if expires, err := http.ParseTime(response.Header.Get("Expires")); err == nil {
+		return expires, true
+	}
+
+	return time.Time{}, false
+}
+
+// migrateFlatLayout moves the bodies written by the previous flat layout
+// (<path>/<url.PathEscape(key)>) to the sharded one. That layout didn't persist
+// the mappings, so the metadata is rebuilt from the stored response headers.
+// The files that aren't lz4 responses, the varied ones and the ones without
+// a fresh lifetime are left untouched, the directory may be shared.
+func (provider *Simplefs) migrateFlatLayout() {
+	files, err := os.ReadDir(provider.path)
+	if err != nil {
+		return
+	}
+
+	now := time.Now()
+	migrated, skipped := 0, 0
+
+	for _, f := range files {
+		if !f.Type().IsRegular() || strings.HasPrefix(f.Name(), ".") {
+			continue
+		}
+
+		key, err := url.PathUnescape(f.Name())
+		if err != nil || strings.Contains(key, varySeparator) {
+			continue
+		}
+
+		oldPath := filepath.Join(provider.path, f.Name())
+
+		response, err := readFlatResponse(oldPath)
+		if err != nil {
+			continue
+		}
+
+		info, err := f.Info()
+		if err != nil {
+			continue
+		}
+
+		fresh, ok := freshUntil(response, info.ModTime())
+		if !ok || !now.Before(fresh) {
+			provider.logger.Debugf("Skip the migration of the flat file %s without fresh lifetime", oldPath)
+
+			skipped++
+
+			continue
+		}
+
+		newPath := provider.filePath(key)
+		if err = os.MkdirAll(filepath.Dir(newPath), 0o777); err != nil {
+			provider.logger.Errorf("Impossible to create the directory for the key %s: %#v", key, err)
+
+			continue
+		}
+
+		metadata := &entryMetadata{
+			Key:        key,
+			BaseKey:    key,
+			RealKey:    key,
+			Etag:       response.Header.Get("Etag"),
+			StoredAt:   info.ModTime(),
+			FreshUntil: fresh,
+			StaleUntil: fresh.Add(provider.stale),
+		}
+
+		// The metadata goes first, the body isn't moved if it can't be indexed.
+		if err = provider.writeMetadata(newPath, metadata); err != nil {
+			provider.logger.Errorf("Impossible to write the metadata for the key %s: %#v", key, err)
+
+			continue
+		}
+
+		if err = os.Rename(oldPath, newPath); err != nil {
+			provider.logger.Errorf("Impossible to migrate the file %s to %s: %#v", oldPath, newPath, err)
+			_ = os.Remove(metadataPath(newPath))
+
+			continue
+		}
+
+		migrated++
+	}
+
+	if migrated+skipped > 0 {
+		provider.logger.Infof("Migrated %d simplefs files of the previous flat layout from %s, left %d without fresh lifetime", migrated, provider.path, skipped)
+	}
+}
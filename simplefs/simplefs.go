@@ -5,11 +5,10 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io"
 	"net/http"
-	"net/url"
 	"os"
 	"path/filepath"
-	"regexp"
 	"strconv"
 	"strings"
 	"sync"
@@ -31,10 +30,24 @@ type Simplefs struct {
 	actualSize    int64
 	directorySize int64
 	mu            sync.Mutex
+	initOnce      sync.Once
+	eviction      evictionPolicy
+	fsync         bool
+	compression   bool
 }
 
 func onEvict(path string) error {
-	return os.Remove(path)
+	_ = os.Remove(metadataPath(path))
+
+	if err := os.Remove(path); err != nil {
+		return err
+	}
+
+	// Drop the shard directories once empty, it fails silently otherwise.
+	_ = os.Remove(filepath.Dir(path))
+	_ = os.Remove(filepath.Dir(filepath.Dir(path)))
+
+	return nil
 }
 
 // Factory function create new Simplefs instance.
@@ -42,6 +55,9 @@ func Factory(simplefsCfg core.CacheProvider, logger core.Logger, stale time.Dura
 	var directorySize int64
 
 	storagePath := simplefsCfg.Path
+	evictionPolicyName := lruPolicy
+	fsync := false
+	compression := true
 	size := 0
 	directorySize = -1
 
@@ -75,6 +91,28 @@ func Factory(simplefsCfg core.CacheProvider, logger core.Logger, stale time.Dura
 					directorySize = int64(s)
 				}
 			}
+
+			if v, found := sfsconfig["fsync"]; found && v != nil {
+				if val, ok := v.(bool); ok {
+					fsync = val
+				} else if val, ok := v.(string); ok {
+					fsync, _ = strconv.ParseBool(val)
+				}
+			}
+
+			if v, found := sfsconfig["compression"]; found && v != nil {
+				if val, ok := v.(bool); ok {
+					compression = val
+				} else if val, ok := v.(string); ok {
+					compression, _ = strconv.ParseBool(val)
+				}
+			}
+
+			if v, found := sfsconfig["eviction_policy"]; found && v != nil {
+				if val, ok := v.(string); ok && val != "" {
+					evictionPolicyName = val
+				}
+			}
 		}
 	}
 
@@ -111,7 +149,18 @@ func Factory(simplefsCfg core.CacheProvider, logger core.Logger, stale time.Dura
 
 	logger.Infof("Created the storage directory %s if needed", storagePath)
 
-	store := Simplefs{cache: cache, directorySize: directorySize, logger: logger, mu: sync.Mutex{}, path: storagePath, size: size, stale: stale}
+	store := Simplefs{
+		cache:         cache,
+		directorySize: directorySize,
+		eviction:      newEvictionPolicy(evictionPolicyName),
+		fsync:         fsync,
+		compression:   compression,
+		logger:        logger,
+		mu:            sync.Mutex{},
+		path:          storagePath,
+		size:          size,
+		stale:         stale,
+	}
 
 	defer func() {
 		go store.cache.Start()
@@ -173,6 +222,8 @@ func (provider *Simplefs) Get(key string) []byte {
 		return result.Value()
 	}
 
+	provider.eviction.touch(key)
+
 	byteValue, err := os.ReadFile(strings.Trim(string(result.Value()), ","))
 	if err != nil {
 		provider.logger.Errorf("Impossible to read the file %s from Simplefs: %#v", result.Value(), err)
@@ -180,70 +231,94 @@ func (provider *Simplefs) Get(key string) []byte {
 		return result.Value()
 	}
 
-	return byteValue
-}
-
-// GetMultiLevel tries to load the key and check if one of linked keys is a fresh/stale candidate.
-func (provider *Simplefs) GetMultiLevel(key string, req *http.Request, validator *core.Revalidator) (fresh *http.Response, stale *http.Response) {
-	provider.mu.Lock()
-
-	val := provider.cache.Get(core.MappingKeyPrefix + key)
+	// The uncompressed bodies are stored without their head, the caller expects the whole compressed response.
+	if entry := provider.eviction.get(key); entry != nil && entry.head != nil {
+		compressed := new(bytes.Buffer)
+		if _, err = lz4.NewWriter(compressed).ReadFrom(io.MultiReader(bytes.NewReader(entry.head), bytes.NewReader(byteValue))); err != nil {
+			provider.logger.Errorf("Impossible to compress the key %s from Simplefs, %v", key, err)
 
-	provider.mu.Unlock()
-
-	if val == nil {
-		provider.logger.Debugf("Impossible to get the mapping key %s in Simplefs", core.MappingKeyPrefix+key)
+			return nil
+		}
 
-		return fresh, stale
+		return compressed.Bytes()
 	}
 
-	fresh, stale, _ = core.MappingElection(provider, val.Value(), req, validator, provider.logger)
-
-	return fresh, stale
+	return byteValue
 }
 
-func (provider *Simplefs) recoverEnoughSpaceIfNeeded(size int64) {
-	if provider.directorySize > -1 && provider.actualSize+size > provider.directorySize {
-		provider.mu.Lock()
-		defer provider.mu.Unlock()
-
-		provider.cache.RangeBackwards(func(item *ttlcache.Item[string, []byte]) bool {
-			// Remove the oldest item if there is not enough space.
-			//nolint:godox
-			// TODO: open a PR to expose a range that iterate on LRU items.
-			provider.cache.Delete(string(item.Value()))
-
-			return false
-		})
-
-		provider.recoverEnoughSpaceIfNeeded(size)
-	}
+// GetMultiLevel tries to load the key and check if one of linked keys is a fresh/stale candidate.
+func (provider *Simplefs) GetMultiLevel(key string, req *http.Request, validator *core.Revalidator) (fresh *http.Response, stale *http.Response) {
+	return provider.getMultiLevel(key, req, validator, provider.loadResponse)
 }
 
 // SetMultiLevel tries to store the key with the given value and update the mapping key to store metadata.
 func (provider *Simplefs) SetMultiLevel(baseKey, variedKey string, value []byte, variedHeaders http.Header, etag string, duration time.Duration, realKey string) error {
 	now := time.Now()
 
-	compressed := new(bytes.Buffer)
-	if _, err := lz4.NewWriter(compressed).ReadFrom(bytes.NewReader(value)); err != nil {
-		provider.logger.Errorf("Impossible to compress the key %s into Simplefs, %v", variedKey, err)
+	var head []byte
 
-		return err
+	content := new(bytes.Buffer)
+
+	if provider.compression {
+		if _, err := lz4.NewWriter(content).ReadFrom(bytes.NewReader(value)); err != nil {
+			provider.logger.Errorf("Impossible to compress the key %s into Simplefs, %v", variedKey, err)
+
+			return err
+		}
+	} else if h, body, ok := splitResponse(value); ok {
+		// Keep the raw body alone in the file so it can be served as is.
+		head = h
+		content = bytes.NewBuffer(body)
+	} else {
+		provider.logger.Errorf("Impossible to find the head of the response %s to store it uncompressed into Simplefs", variedKey)
+
+		return errors.New("invalid response, no head found")
 	}
 
-	provider.recoverEnoughSpaceIfNeeded(int64(compressed.Len()))
+	provider.mu.Lock()
+	provider.recoverEnoughSpaceIfNeeded(int64(content.Len()))
+	provider.mu.Unlock()
+
+	joinedFP := provider.filePath(variedKey)
+	if err := os.MkdirAll(filepath.Dir(joinedFP), 0o777); err != nil {
+		provider.logger.Errorf("Impossible to create the directory for the key %s in Simplefs: %#v", variedKey, err)
 
-	joinedFP := filepath.Join(provider.path, url.PathEscape(variedKey))
-	//nolint:gosec
-	if err := os.WriteFile(joinedFP, compressed.Bytes(), 0o644); err != nil {
+		return nil
+	}
+
+	if err := provider.writeFile(joinedFP, content.Bytes()); err != nil {
 		provider.logger.Errorf("Impossible to write the file %s from Simplefs: %#v", variedKey, err)
 
 		return nil
 	}
 
+	metadata := &entryMetadata{
+		Key:           variedKey,
+		BaseKey:       baseKey,
+		RealKey:       realKey,
+		Etag:          etag,
+		VariedHeaders: variedHeaders,
+		StoredAt:      now,
+		FreshUntil:    now.Add(duration),
+		StaleUntil:    now.Add(duration + provider.stale),
+		Head:          head,
+	}
+	if err := provider.writeMetadata(joinedFP, metadata); err != nil {
+		provider.logger.Errorf("Impossible to write the metadata %s from Simplefs: %#v", variedKey, err)
+	}
+
 	provider.mu.Lock()
 	defer provider.mu.Unlock()
-	_ = provider.cache.Set(variedKey, []byte(joinedFP), duration)
+
+	stored := provider.cache.Set(variedKey, []byte(joinedFP), duration)
+	provider.track(&policyEntry{
+		key:     variedKey,
+		baseKey: baseKey,
+		path:    joinedFP,
+		size:    int64(content.Len()),
+		head:    head,
+		item:    stored,
+	})
 
 	mappingKey := core.MappingKeyPrefix + baseKey
 	item := provider.cache.Get(mappingKey)
@@ -291,13 +366,13 @@ func (provider *Simplefs) Delete(key string) {
 
 // DeleteMany method will delete the responses in Simplefs provider if exists corresponding to the regex key param.
 func (provider *Simplefs) DeleteMany(key string) {
-	rgKey, e := regexp.Compile(key)
+	matcher, e := core.ParseMatcher(key)
 	if e != nil {
 		return
 	}
 
 	provider.cache.Range(func(item *ttlcache.Item[string, []byte]) bool {
-		if rgKey.MatchString(item.Key()) {
+		if matcher.Match(item.Key()) {
 			provider.Delete(item.Key())
 		}
 
@@ -305,60 +380,27 @@ func (provider *Simplefs) DeleteMany(key string) {
 	})
 }
 
-// Init method will.
+// Init method will register the eviction hook and rebuild the index from the disk.
 func (provider *Simplefs) Init() error {
-	provider.cache.OnInsertion(func(_ context.Context, item *ttlcache.Item[string, []byte]) {
-		if strings.Contains(item.Key(), core.MappingKeyPrefix) || strings.Contains(item.Key(), core.SurrogateKeyPrefix) {
-			return
-		}
-
-		info, err := os.Stat(string(item.Value()))
-		if err != nil {
-			provider.logger.Errorf("impossible to get the file size %s: %#v", item.Key(), err)
+	provider.initOnce.Do(provider.init)
 
-			return
-		}
-
-		provider.mu.Lock()
-		provider.actualSize += info.Size()
-		provider.logger.Debugf("Actual size add: %d, new: %d", provider.actualSize, info.Size())
-		provider.mu.Unlock()
-	})
+	return nil
+}
 
+func (provider *Simplefs) init() {
 	provider.cache.OnEviction(func(_ context.Context, _ ttlcache.EvictionReason, item *ttlcache.Item[string, []byte]) {
-		if strings.Contains(string(item.Value()), core.MappingKeyPrefix) {
-			return
-		}
-
-		info, err := os.Stat(string(item.Value()))
-		if err != nil {
-			provider.logger.Errorf("impossible to get the file size %s: %#v", item.Key(), err)
-
-			return
-		}
-
 		provider.mu.Lock()
-		provider.actualSize -= info.Size()
-		provider.logger.Debugf("Actual size remove: %d, new: %d", provider.actualSize, info.Size())
-		provider.mu.Unlock()
+		defer provider.mu.Unlock()
 
-		if err := onEvict(string(item.Value())); err != nil {
-			provider.logger.Errorf("impossible to remove the file %s: %#v", item.Key(), err)
+		// Skip the mappings, the in-memory values and the bodies replaced since the eviction.
+		if entry := provider.eviction.get(item.Key()); entry != nil && entry.item == item {
+			provider.forget(item.Key())
 		}
 	})
 
-	files, _ := os.ReadDir(provider.path)
+	provider.migrateFlatLayout()
 	provider.logger.Debugf("Regenerating simplefs cache from files in the given directory.")
-
-	for _, f := range files {
-		if !f.IsDir() {
-			info, _ := f.Info()
-			provider.actualSize += info.Size()
-			provider.logger.Debugf("Add %v bytes to the actual size, sum to %v bytes.", info.Size(), provider.actualSize)
-		}
-	}
-
-	return nil
+	provider.rebuildIndex()
 }
 
 // Reset method will reset or close provider.
@@ -1,13 +1,23 @@
 package simplefs_test
 
 import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
+	"io"
 	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/darkweak/storages/core"
 	"github.com/darkweak/storages/simplefs"
+	"github.com/pierrec/lz4/v4"
 	"go.uber.org/zap"
 )
 
@@ -158,3 +168,297 @@ func TestSimplefs_EvictAfterXSeconds(t *testing.T) {
 
 	time.Sleep(3 * time.Second)
 }
+
+func getSimplefsInstanceInDirectory(t *testing.T, directory string) core.Storer {
+	t.Helper()
+
+	client, err := simplefs.Factory(core.CacheProvider{
+		Configuration: map[string]interface{}{
+			"path": directory,
+		},
+	}, zap.NewNop().Sugar(), 0)
+	if err != nil {
+		t.Fatalf("Impossible to instantiate Simplefs: %v", err)
+	}
+
+	_ = client.Init()
+
+	return client
+}
+
+func shardedPath(directory, key string) string {
+	sum := sha256.Sum256([]byte(key))
+	name := hex.EncodeToString(sum[:])
+
+	return filepath.Join(directory, name[:2], name[2:4], name)
+}
+
+func TestSimplefs_ShardedLayout(t *testing.T) {
+	directory := t.TempDir()
+	client := getSimplefsInstanceInDirectory(t, directory)
+	key := strings.Repeat("a-very-long-key/", 50)
+
+	if err := client.SetMultiLevel(key, key, []byte(baseValue), http.Header{}, "", 20*time.Second, key); err != nil {
+		t.Errorf("Impossible to store the key: %v", err)
+	}
+
+	if _, err := os.Stat(shardedPath(directory, key)); err != nil {
+		t.Errorf("The body should be stored in the sharded directory: %v", err)
+	}
+
+	metadata, err := os.ReadFile(shardedPath(directory, key) + ".meta")
+	if err != nil || !strings.Contains(string(metadata), key) {
+		t.Errorf("The metadata should contain the original key, got %s: %v", metadata, err)
+	}
+}
+
+func TestSimplefs_KeepUnrelatedFiles(t *testing.T) {
+	directory := t.TempDir()
+	flatKey := "GET-http-example.com-/flat?page=1"
+	orphan := shardedPath(directory, "Orphan")
+
+	compressed := new(bytes.Buffer)
+	_, _ = lz4.NewWriter(compressed).ReadFrom(strings.NewReader(baseValue))
+
+	unrelated := []string{
+		filepath.Join(directory, url.PathEscape(flatKey)),
+		filepath.Join(directory, "src", "pkg", "main.go"),
+		filepath.Join(filepath.Dir(orphan), "notes.txt"),
+		filepath.Join(directory, "ab", "cd", filepath.Base(orphan)),
+	}
+
+	for _, path := range append(unrelated, orphan) {
+		_ = os.MkdirAll(filepath.Dir(path), 0o777)
+		_ = os.WriteFile(path, compressed.Bytes(), 0o600)
+	}
+
+	_ = getSimplefsInstanceInDirectory(t, directory)
+
+	for _, path := range unrelated {
+		if _, err := os.Stat(path); err != nil {
+			t.Errorf("The unrelated file %s should not be removed: %v", path, err)
+		}
+	}
+
+	if _, err := os.Stat(orphan); !os.IsNotExist(err) {
+		t.Error("The sharded body without metadata should have been removed")
+	}
+}
+
+func TestSimplefs_MigrateFlatLayout(t *testing.T) {
+	directory := t.TempDir()
+	date := time.Now().UTC().Format(http.TimeFormat)
+	flat := map[string]string{
+		"GET-http-example.com-/flat?page=1": "Cache-Control: public, max-age=60\r\nDate: " + date,
+		"GET-http-example.com-/unknown":     "Date: " + date,
+		"GET-http-example.com-/expired":     "Cache-Control: max-age=60\r\nDate: Mon, 02 Jan 2006 15:04:05 GMT",
+	}
+
+	for key, headers := range flat {
+		compressed := new(bytes.Buffer)
+		_, _ = lz4.NewWriter(compressed).ReadFrom(strings.NewReader("HTTP/1.1 200 OK\r\n" + headers + "\r\nContent-Length: 13\r\n\r\n" + baseValue))
+		_ = os.WriteFile(filepath.Join(directory, url.PathEscape(key)), compressed.Bytes(), 0o600)
+	}
+
+	client := getSimplefsInstanceInDirectory(t, directory)
+
+	if _, err := os.Stat(shardedPath(directory, "GET-http-example.com-/flat?page=1")); err != nil {
+		t.Errorf("The flat file should have been moved to the sharded layout: %v", err)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/flat?page=1", nil)
+	fresh, _ := client.GetMultiLevel("GET-http-example.com-/flat?page=1", req, &core.Revalidator{})
+
+	if fresh == nil {
+		t.Fatal("The migrated entry should be served as fresh")
+	}
+
+	if body, _ := io.ReadAll(fresh.Body); string(body) != baseValue {
+		t.Errorf("The migrated body %s doesn't match %s", body, baseValue)
+	}
+
+	// Their lifetime is unknown or over, they are left where they are.
+	for _, key := range []string{"GET-http-example.com-/unknown", "GET-http-example.com-/expired"} {
+		if _, err := os.Stat(filepath.Join(directory, url.PathEscape(key))); err != nil {
+			t.Errorf("The flat file %s should not be touched: %v", key, err)
+		}
+	}
+}
+
+func TestSimplefs_RebuildIndexOnStartup(t *testing.T) {
+	directory := t.TempDir()
+	client := getSimplefsInstanceInDirectory(t, directory)
+	_ = client.SetMultiLevel("Persisted", "Persisted-variant", []byte("HTTP/1.1 200 OK\r\nContent-Length: 13\r\n\r\n"+baseValue), http.Header{}, "", 20*time.Second, "Persisted")
+	_ = client.SetMultiLevel("Expired", "Expired-variant", []byte(baseValue), http.Header{}, "", time.Second, "Expired")
+
+	orphan := shardedPath(directory, "Orphan")
+	_ = os.MkdirAll(filepath.Dir(orphan), 0o777)
+	_ = os.WriteFile(orphan, []byte(baseValue), 0o600)
+
+	time.Sleep(1500 * time.Millisecond)
+
+	restarted := getSimplefsInstanceInDirectory(t, directory)
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	fresh, _ := restarted.GetMultiLevel("Persisted", req, &core.Revalidator{})
+
+	if fresh == nil {
+		t.Fatal("The persisted entry should be restored as fresh")
+	}
+
+	if body, _ := io.ReadAll(fresh.Body); string(body) != baseValue {
+		t.Errorf("The restored body %s doesn't match %s", body, baseValue)
+	}
+
+	if _, err := os.Stat(orphan); !os.IsNotExist(err) {
+		t.Error("The orphaned file should have been removed")
+	}
+
+	if len(restarted.Get("Expired-variant")) != 0 {
+		t.Error("The expired entry should not be restored")
+	}
+}
+
+func testSimplefsEviction(t *testing.T, policy string, use func(client core.Storer), evicted, kept string) {
+	t.Helper()
+
+	compressed := new(bytes.Buffer)
+	_, _ = lz4.NewWriter(compressed).ReadFrom(strings.NewReader(baseValue))
+
+	client, _ := simplefs.Factory(core.CacheProvider{
+		Configuration: map[string]interface{}{
+			"path":            t.TempDir(),
+			"directory_size":  float64(compressed.Len()*2 + compressed.Len()/2),
+			"eviction_policy": policy,
+		},
+	}, zap.NewNop().Sugar(), 0)
+	_ = client.Init()
+
+	for _, key := range []string{"A", "B"} {
+		_ = client.SetMultiLevel(key, key, []byte(baseValue), http.Header{}, "", 20*time.Second, key)
+	}
+
+	use(client)
+
+	_ = client.SetMultiLevel("C", "C", []byte(baseValue), http.Header{}, "", 20*time.Second, "C")
+
+	if len(client.Get(evicted)) != 0 {
+		t.Errorf("Key %s should be evicted with the %s policy", evicted, policy)
+	}
+
+	if len(client.Get(kept)) == 0 {
+		t.Errorf("Key %s should be kept with the %s policy", kept, policy)
+	}
+
+	if _, found := client.MapKeys(core.MappingKeyPrefix)[evicted]; found {
+		t.Errorf("The mapping of the evicted key %s should be removed", evicted)
+	}
+}
+
+func TestSimplefs_LRUEviction(t *testing.T) {
+	testSimplefsEviction(t, "lru", func(client core.Storer) {
+		_ = client.Get("A")
+	}, "B", "A")
+}
+
+func TestSimplefs_LFUEviction(t *testing.T) {
+	testSimplefsEviction(t, "lfu", func(client core.Storer) {
+		_ = client.Get("B")
+		_ = client.Get("B")
+		_ = client.Get("A")
+	}, "A", "B")
+}
+
+func TestSimplefs_AtomicWrites(t *testing.T) {
+	directory := t.TempDir()
+	body := shardedPath(directory, "Leftover")
+	leftover := filepath.Join(filepath.Dir(body), ".tmp-"+filepath.Base(body)+"-123")
+	_ = os.MkdirAll(filepath.Dir(leftover), 0o777)
+	_ = os.WriteFile(leftover, []byte(baseValue), 0o600)
+
+	client, _ := simplefs.Factory(core.CacheProvider{
+		Configuration: map[string]interface{}{
+			"path":  directory,
+			"fsync": true,
+		},
+	}, zap.NewNop().Sugar(), 0)
+	_ = client.Init()
+
+	if _, err := os.Stat(leftover); !os.IsNotExist(err) {
+		t.Error("The leftover temporary file should have been removed on startup")
+	}
+
+	if err := client.SetMultiLevel("Atomic", "Atomic", []byte(baseValue), http.Header{}, "", 20*time.Second, "Atomic"); err != nil {
+		t.Errorf("Impossible to store the key: %v", err)
+	}
+
+	entries, _ := os.ReadDir(filepath.Dir(shardedPath(directory, "Atomic")))
+	for _, entry := range entries {
+		if strings.HasPrefix(entry.Name(), ".tmp-") {
+			t.Errorf("The temporary file %s should have been renamed", entry.Name())
+		}
+	}
+
+	if len(client.Get("Atomic")) == 0 {
+		t.Error("Key Atomic should exist")
+	}
+}
+
+func TestSimplefs_StreamedResponses(t *testing.T) {
+	response := "HTTP/1.1 200 OK\r\nContent-Length: 13\r\nContent-Type: text/plain\r\n\r\n" + baseValue
+
+	for _, compression := range []bool{true, false} {
+		client, _ := simplefs.Factory(core.CacheProvider{
+			Configuration: map[string]interface{}{
+				"path":        t.TempDir(),
+				"compression": compression,
+			},
+		}, zap.NewNop().Sugar(), 0)
+		_ = client.Init()
+
+		_ = client.SetMultiLevel("Stream", "Stream", []byte(response), http.Header{}, "", 20*time.Second, "Stream")
+
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		fresh, _ := client.(*simplefs.Simplefs).GetMultiLevelStream("Stream", req, &core.Revalidator{})
+
+		if fresh == nil {
+			t.Fatalf("The streamed response should exist with compression %t", compression)
+		}
+
+		if _, isFile := fresh.Body.(*os.File); isFile == compression {
+			t.Errorf("The body should be the open file only when stored uncompressed, compression %t", compression)
+		}
+
+		body, _ := io.ReadAll(fresh.Body)
+		_ = fresh.Body.Close()
+
+		if string(body) != baseValue || fresh.Header.Get("Content-Type") != "text/plain" {
+			t.Errorf("The streamed response doesn't match, got %s with compression %t", body, compression)
+		}
+
+		fresh, _ = client.GetMultiLevel("Stream", req, &core.Revalidator{})
+		if body, _ = io.ReadAll(fresh.Body); string(body) != baseValue {
+			t.Errorf("The in-memory response doesn't match, got %s with compression %t", body, compression)
+		}
+
+		decompressed := new(bytes.Buffer)
+		_, _ = lz4.NewReader(bytes.NewReader(client.Get("Stream"))).WriteTo(decompressed)
+
+		if decompressed.String() != response {
+			t.Errorf("Get should return the whole compressed response, got %s with compression %t", decompressed, compression)
+		}
+	}
+}
+
+func TestSimplefs_OpenResponseRemovedFile(t *testing.T) {
+	directory := t.TempDir()
+	client := getSimplefsInstanceInDirectory(t, directory)
+	_ = client.SetMultiLevel("Removed", "Removed", []byte("HTTP/1.1 200 OK\r\nContent-Length: 13\r\n\r\n"+baseValue), http.Header{}, "", 20*time.Second, "Removed")
+
+	_ = os.Remove(shardedPath(directory, "Removed"))
+
+	response, err := client.(*simplefs.Simplefs).OpenResponse("Removed", httptest.NewRequest(http.MethodGet, "/", nil))
+	if response != nil || err != nil {
+		t.Errorf("The removed body should be skipped, got %v: %v", response, err)
+	}
+}
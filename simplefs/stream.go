@@ -0,0 +1,154 @@
+package simplefs
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+
+	"github.com/darkweak/storages/core"
+	"github.com/pierrec/lz4/v4"
+)
+
+var headSeparator = []byte("\r\n\r\n")
+
+// splitResponse splits the raw response into its head (status line and headers) and its body.
+func splitResponse(value []byte) (head []byte, body []byte, ok bool) {
+	index := bytes.Index(value, headSeparator)
+	if index < 0 {
+		return nil, nil, false
+	}
+
+	index += len(headSeparator)
+
+	return value[:index], value[index:], true
+}
+
+// fileBody closes the underlying file with the response body.
+type fileBody struct {
+	io.ReadCloser
+	file *os.File
+}
+
+func (b *fileBody) Close() error {
+	_ = b.ReadCloser.Close()
+
+	return b.file.Close()
+}
+
+// open returns the tracked entry of the key with its opened body file, or a
+// nil entry if the key isn't in the cache anymore. The file is opened under
+// the lock so forget and the evictions can't remove it in between, the open
+// file stays readable once removed.
+func (provider *Simplefs) open(key string) (*policyEntry, *os.File, error) {
+	provider.mu.Lock()
+	defer provider.mu.Unlock()
+
+	if provider.cache.Get(key) == nil {
+		return nil, nil, nil
+	}
+
+	provider.eviction.touch(key)
+
+	entry := provider.eviction.get(key)
+	if entry == nil {
+		return nil, nil, nil
+	}
+
+	file, err := os.Open(entry.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		// Removed outside of the provider, the variant is skipped.
+		return nil, nil, nil
+	}
+
+	return entry, file, err
+}
+
+// OpenResponse returns the response stored under the key with a body streamed from the open file,
+// or a nil response if the key doesn't exist. The caller must close the response body.
+// When the body is stored uncompressed and not chunked, the response body is the *os.File itself,
+// so it can be given to http.ServeContent or copied to the connection with sendfile.
+func (provider *Simplefs) OpenResponse(key string, req *http.Request) (*http.Response, error) {
+	entry, file, err := provider.open(key)
+	if entry == nil || err != nil {
+		return nil, err
+	}
+
+	var reader io.Reader = lz4.NewReader(file)
+
+	if entry.head != nil {
+		response, err := http.ReadResponse(bufio.NewReader(bytes.NewReader(entry.head)), req)
+		if err != nil {
+			_ = file.Close()
+
+			return nil, err
+		}
+
+		if len(response.TransferEncoding) == 0 {
+			response.Body = file
+
+			return response, nil
+		}
+
+		reader = io.MultiReader(bytes.NewReader(entry.head), file)
+	}
+
+	response, err := http.ReadResponse(bufio.NewReader(reader), req)
+	if err != nil {
+		_ = file.Close()
+
+		return nil, err
+	}
+
+	response.Body = &fileBody{ReadCloser: response.Body, file: file}
+
+	return response, nil
+}
+
+// loadResponse returns the response stored under the key with its body read in memory.
+func (provider *Simplefs) loadResponse(key string, req *http.Request) (*http.Response, error) {
+	response, err := provider.OpenResponse(key, req)
+	if err != nil || response == nil {
+		return response, err
+	}
+
+	defer func() {
+		_ = response.Body.Close()
+	}()
+
+	body, err := io.ReadAll(response.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	response.Body = io.NopCloser(bytes.NewReader(body))
+
+	return response, nil
+}
+
+// GetMultiLevelStream works like GetMultiLevel but the bodies of the returned responses are streamed
+// from the open files. The caller must close the response bodies.
+func (provider *Simplefs) GetMultiLevelStream(key string, req *http.Request, validator *core.Revalidator) (fresh *http.Response, stale *http.Response) {
+	return provider.getMultiLevel(key, req, validator, provider.OpenResponse)
+}
+
+func (provider *Simplefs) getMultiLevel(key string, req *http.Request, validator *core.Revalidator, load core.ResponseLoader) (fresh *http.Response, stale *http.Response) {
+	provider.mu.Lock()
+
+	val := provider.cache.Get(core.MappingKeyPrefix + key)
+
+	provider.mu.Unlock()
+
+	if val == nil {
+		provider.logger.Debugf("Impossible to get the mapping key %s in Simplefs", core.MappingKeyPrefix+key)
+
+		return fresh, stale
+	}
+
+	fresh, stale, _ = core.MappingElectionWithLoader(val.Value(), req, validator, provider.logger, load)
+
+	return fresh, stale
+}
@@ -0,0 +1,76 @@
+package simplefs
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Prefix of the files being written, they are renamed once complete.
+const tempFilePrefix = ".tmp-"
+
+func isTempFile(name string) bool {
+	return strings.HasPrefix(name, tempFilePrefix)
+}
+
+// writeFile writes the content to a temporary file in the destination directory
+// and renames it to the final path. The rename is atomic, so the readers either
+// see the previous file or the complete new one, never a partial write.
+func (provider *Simplefs) writeFile(path string, content []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), tempFilePrefix+filepath.Base(path)+"-*")
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		// No-op once renamed.
+		_ = os.Remove(tmp.Name())
+	}()
+
+	if _, err = tmp.Write(content); err != nil {
+		_ = tmp.Close()
+
+		return err
+	}
+
+	if provider.fsync {
+		if err = tmp.Sync(); err != nil {
+			_ = tmp.Close()
+
+			return err
+		}
+	}
+
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+
+	//nolint:gosec
+	if err = os.Chmod(tmp.Name(), 0o644); err != nil {
+		return err
+	}
+
+	if err = os.Rename(tmp.Name(), path); err != nil {
+		return err
+	}
+
+	if provider.fsync {
+		return syncDir(filepath.Dir(path))
+	}
+
+	return nil
+}
+
+// syncDir flushes the directory entry to make the rename durable.
+func syncDir(path string) error {
+	dir, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		_ = dir.Close()
+	}()
+
+	return dir.Sync()
+}